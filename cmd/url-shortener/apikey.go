@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"url-shortener/internal/config1"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/storage"
+)
+
+const apiKeyUsage = `usage:
+  url-shortener apikey create -user <user name> -name <name> [-scopes read,write,delete]
+  url-shortener apikey list
+  url-shortener apikey revoke <id>`
+
+// apiKeyManager is what the apikey subcommand needs from the storage
+type apiKeyManager interface {
+	SaveAPIKey(ctx context.Context, k storage.APIKey) (int64, error)
+	ListAPIKeys(ctx context.Context) ([]storage.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64, at time.Time) error
+}
+
+// runAPIKey handles `url-shortener apikey <command>`
+func runAPIKey(cfg *config1.Config, out io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+	if cfg.StorageType == config1.StorageMemory {
+		return errors.New("api keys of the memory storage live only inside the running server, set auth.bootstrap_key to get an admin key")
+	}
+
+	store, err := setupStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	return apiKeyCommand(context.Background(), store, store, out, args)
+}
+
+func apiKeyCommand(ctx context.Context, keys apiKeyManager, users userManager, out io.Writer, args []string) error {
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("create", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		userName := fs.String("user", "", "owner of the key, see `url-shortener user create`")
+		name := fs.String("name", "", "what the key is for")
+		scopesFlag := fs.String("scopes", apikey.ScopeRead, "comma separated scopes")
+		if err := fs.Parse(args[1:]); err != nil || *userName == "" || *name == "" || fs.NArg() != 0 {
+			return errors.New(apiKeyUsage)
+		}
+
+		user, err := users.GetUserByName(ctx, *userName)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return fmt.Errorf("no user %q, create it with `url-shortener user create -name %s`", *userName, *userName)
+			}
+			return err
+		}
+
+		scopes, err := apikey.ParseScopes(*scopesFlag)
+		if err != nil {
+			return err
+		}
+
+		token, err := apikey.Generate()
+		if err != nil {
+			return err
+		}
+
+		id, err := keys.SaveAPIKey(ctx, storage.APIKey{
+			UserID: user.ID,
+			Name:   *name,
+			Prefix: apikey.Prefix(token),
+			Hash:   apikey.Hash(token),
+			Scopes: scopes,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "created key %d (%s) for %s with scopes %s\n", id, *name, user.Name, strings.Join(scopes, ","))
+		fmt.Fprintf(out, "%s\n", token)
+		fmt.Fprintln(out, "the key is shown only once, store it now")
+	case "list":
+		if len(args) != 1 {
+			return errors.New(apiKeyUsage)
+		}
+
+		list, err := keys.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSER ID\tNAME\tPREFIX\tSCOPES\tCREATED AT\tREVOKED AT")
+		for _, k := range list {
+			revokedAt := "-"
+			if k.Revoked() {
+				revokedAt = k.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\n",
+				k.ID, k.UserID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), k.CreatedAt.Format(time.RFC3339), revokedAt)
+		}
+		return w.Flush()
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(apiKeyUsage)
+		}
+
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid key id %q", args[1])
+		}
+
+		if err := keys.RevokeAPIKey(ctx, id, time.Now()); err != nil {
+			if errors.Is(err, storage.ErrAPIKeyNotFound) {
+				return fmt.Errorf("no active key with id %d", id)
+			}
+			return err
+		}
+
+		fmt.Fprintf(out, "revoked key %d\n", id)
+	default:
+		return errors.New(apiKeyUsage)
+	}
+
+	return nil
+}
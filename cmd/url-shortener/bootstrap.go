@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/storage"
+)
+
+const (
+	// bootstrapUser owns the key from auth.bootstrap_key
+	bootstrapUser    = "admin"
+	bootstrapKeyName = "bootstrap"
+	// bootstrapPrefixLength is how much of the hash tells bootstrap keys apart
+	bootstrapPrefixLength = 8
+)
+
+// bootstrapKeys is what bootstrapAdmin needs from the storage besides users
+type bootstrapKeys interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error)
+	SaveAPIKey(ctx context.Context, k storage.APIKey) (int64, error)
+}
+
+// bootstrapAdmin makes sure the token is a key of an admin with every scope.
+// An existing key is returned as is, so a revoked bootstrap key stays revoked.
+func bootstrapAdmin(ctx context.Context, keys bootstrapKeys, users userManager, token string) (storage.APIKey, bool, error) {
+	const op = "main.bootstrapAdmin"
+
+	hash := apikey.Hash(token)
+
+	key, err := keys.GetAPIKeyByHash(ctx, hash)
+	if err == nil {
+		return key, false, nil
+	}
+	if !errors.Is(err, storage.ErrAPIKeyNotFound) {
+		return storage.APIKey{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := bootstrapAdminUser(ctx, users)
+	if err != nil {
+		return storage.APIKey{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key = storage.APIKey{
+		UserID: user.ID,
+		Name:   bootstrapKeyName,
+		Prefix: bootstrapPrefix(hash),
+		Hash:   hash,
+		Scopes: apikey.Scopes,
+	}
+	key.ID, err = keys.SaveAPIKey(ctx, key)
+	if err != nil {
+		return storage.APIKey{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, true, nil
+}
+
+// bootstrapAdminUser returns the admin user, creating it on the first start
+func bootstrapAdminUser(ctx context.Context, users userManager) (storage.User, error) {
+	user, err := users.GetUserByName(ctx, bootstrapUser)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		user = storage.User{Name: bootstrapUser, Role: storage.RoleAdmin}
+		user.ID, err = users.SaveUser(ctx, user)
+		// другой экземпляр сервера успел создать пользователя
+		if errors.Is(err, storage.ErrUserExists) {
+			user, err = users.GetUserByName(ctx, bootstrapUser)
+		}
+	}
+	if err != nil {
+		return storage.User{}, err
+	}
+
+	// роль существующего пользователя не повышаем молча
+	if !user.IsAdmin() {
+		return storage.User{}, fmt.Errorf("user %q exists with role %s, the bootstrap key needs an admin", user.Name, user.Role)
+	}
+
+	return user, nil
+}
+
+// bootstrapPrefix identifies the bootstrap key in listings. The key is chosen
+// by the operator and may be a reused secret, so unlike generated keys none
+// of it is kept in plain text, the prefix is taken from its hash.
+func bootstrapPrefix(hash string) string {
+	return "sha256:" + hash[:bootstrapPrefixLength]
+}
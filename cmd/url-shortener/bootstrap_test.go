@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBootstrapKey = "0123456789abcdef0123456789abcdef"
+
+func TestBootstrapAdmin(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	key, created, err := bootstrapAdmin(ctx, store, store, testBootstrapKey)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.ElementsMatch(t, apikey.Scopes, key.Scopes)
+
+	// ни одного символа ключа не сохраняется открытым текстом
+	assert.Equal(t, "sha256:"+apikey.Hash(testBootstrapKey)[:8], key.Prefix)
+
+	// ключ находится так же, как его ищет auth
+	got, err := store.GetAPIKeyByHash(ctx, apikey.Hash(testBootstrapKey))
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, got.ID)
+
+	user, err := store.GetUser(ctx, got.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, bootstrapUser, user.Name)
+	assert.True(t, user.IsAdmin())
+
+	// повторный запуск ничего не создаёт
+	again, created, err := bootstrapAdmin(ctx, store, store, testBootstrapKey)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, key.ID, again.ID)
+
+	// новый ключ достаётся тому же администратору
+	other, created, err := bootstrapAdmin(ctx, store, store, testBootstrapKey+"-rotated")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, user.ID, other.UserID)
+}
+
+func TestBootstrapAdminKeepsRoleOfExistingUser(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	_, err := store.SaveUser(ctx, storage.User{Name: bootstrapUser, Role: storage.RoleViewer})
+	require.NoError(t, err)
+
+	_, _, err = bootstrapAdmin(ctx, store, store, testBootstrapKey)
+	require.Error(t, err)
+
+	_, err = store.GetAPIKeyByHash(ctx, apikey.Hash(testBootstrapKey))
+	assert.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+}
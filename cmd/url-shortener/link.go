@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"url-shortener/internal/config1"
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/stats"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/transfer"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const linkUsage = `usage:
+  url-shortener link create -url <url> [-alias <alias>] [-code 301|302|307|308] [-expires <RFC3339> | -ttl <duration>] [-owner <user name>]
+  url-shortener link get <alias>
+  url-shortener link delete <alias>
+  url-shortener link list [-owner <user name>] [-query <text>] [-sort id|alias|url|created_at] [-desc] [-limit 50] [-offset 0]
+  url-shortener link stats [-days 30] <alias>
+  url-shortener link export [-file <path>] [-format jsonl|csv]
+  url-shortener link import [-file <path>] [-format jsonl|csv|bitly|rebrandly|shortio|tinyurl] [-on-conflict skip|overwrite|rename] [-owner <user name>] [-quiet]
+
+"-" or no file means stdout and stdin, the format is taken from the file
+extension when it is not set: .csv is csv, anything else jsonl. Vendor
+formats read CSV exports of other shorteners.
+
+The commands change the database directly. A running server keeps
+redirecting deleted and overwritten links from its cache for up to
+cache.ttl, drop them at once with an admin key:
+  curl -X DELETE -H "Authorization: Bearer <key>" <server>/url/<alias>/cache`
+
+// linkManager is what the link subcommand needs from the storage
+type linkManager interface {
+	storage.URLStorage
+	stats.ClickStatsGetter
+	transfer.URLSaver
+}
+
+// runLink handles `url-shortener link <command>`. It works on the storage
+// directly, so it needs no api key and works while the server is down.
+func runLink(cfg *config1.Config, in io.Reader, out io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(linkUsage)
+	}
+	if cfg.StorageType == config1.StorageMemory {
+		return errors.New("links of the memory storage live only inside the running server")
+	}
+
+	store, err := setupStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	aliases, err := setupAliasGenerator(slogdiscard.NewDiscardLogger(), cfg, store)
+	if err != nil {
+		return err
+	}
+
+	policy, err := setupAliasPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
+	return linkCommand(context.Background(), store, store, aliases, policy, in, out, args)
+}
+
+func linkCommand(ctx context.Context, links linkManager, users userManager, aliases save.AliasGenerator, policy save.AliasPolicy, in io.Reader, out io.Writer, args []string) error {
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("create", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		target := fs.String("url", "", "target url")
+		aliasFlag := fs.String("alias", "", "alias, generated when empty")
+		code := fs.Int("code", 0, "redirect status, the server default when zero")
+		expires := fs.String("expires", "", "expiry moment in RFC3339")
+		ttl := fs.String("ttl", "", "lifetime, e.g. 720h")
+		owner := fs.String("owner", "", "user name of the owner")
+		if err := fs.Parse(args[1:]); err != nil || *target == "" || fs.NArg() != 0 {
+			return errors.New(linkUsage)
+		}
+
+		req := save.Request{URL: *target, Alias: *aliasFlag, RedirectCode: *code, TTL: *ttl}
+		if *expires != "" {
+			t, err := time.Parse(time.RFC3339, *expires)
+			if err != nil {
+				return fmt.Errorf("invalid -expires %q, expected RFC3339", *expires)
+			}
+			req.ExpiresAt = &t
+		}
+
+		ownerID, err := lookupOwner(ctx, users, *owner)
+		if err != nil {
+			return err
+		}
+
+		u, err := createLink(ctx, links, save.NewValidator(policy), aliases, policy, req, ownerID)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "created link %d: %s -> %s\n", u.ID, u.Alias, u.URL)
+	case "get":
+		if len(args) != 2 {
+			return errors.New(linkUsage)
+		}
+
+		alias := policy.Normalize(args[1])
+		u, err := links.GetURL(ctx, alias)
+		if err != nil {
+			if errors.Is(err, storage.ErrURLNotFound) {
+				return fmt.Errorf("no link %q", alias)
+			}
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "id:\t%d\n", u.ID)
+		fmt.Fprintf(w, "alias:\t%s\n", u.Alias)
+		fmt.Fprintf(w, "url:\t%s\n", u.URL)
+		fmt.Fprintf(w, "redirect code:\t%s\n", redirectCode(u.RedirectCode))
+		fmt.Fprintf(w, "created at:\t%s\n", formatTime(u.CreatedAt))
+		fmt.Fprintf(w, "expires at:\t%s\n", formatTime(u.ExpiresAt))
+		fmt.Fprintf(w, "owner id:\t%s\n", formatID(u.OwnerID))
+		return w.Flush()
+	case "delete":
+		if len(args) != 2 {
+			return errors.New(linkUsage)
+		}
+
+		alias := policy.Normalize(args[1])
+		if err := links.DeleteURL(ctx, alias); err != nil {
+			if errors.Is(err, storage.ErrURLNotFound) {
+				return fmt.Errorf("no link %q", alias)
+			}
+			return err
+		}
+
+		fmt.Fprintf(out, "deleted link %s\n", alias)
+	case "list":
+		fs := flag.NewFlagSet("list", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		owner := fs.String("owner", "", "user name of the owner")
+		query := fs.String("query", "", "part of the alias or url")
+		sortBy := fs.String("sort", storage.SortByID, "id, alias, url or created_at")
+		desc := fs.Bool("desc", false, "sort in descending order")
+		limit := fs.Int("limit", 50, "links per page")
+		offset := fs.Int("offset", 0, "links to skip")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 || *limit <= 0 || *offset < 0 {
+			return errors.New(linkUsage)
+		}
+		switch *sortBy {
+		case storage.SortByID, storage.SortByAlias, storage.SortByURL, storage.SortByCreatedAt:
+		default:
+			return fmt.Errorf("unknown sort key %q", *sortBy)
+		}
+
+		ownerID, err := lookupOwner(ctx, users, *owner)
+		if err != nil {
+			return err
+		}
+
+		list, total, err := links.ListURLs(ctx, storage.ListParams{
+			Limit:   *limit,
+			Offset:  *offset,
+			SortBy:  *sortBy,
+			Desc:    *desc,
+			Query:   *query,
+			OwnerID: ownerID,
+		})
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tALIAS\tURL\tCODE\tCREATED AT\tEXPIRES AT\tOWNER")
+		for _, u := range list {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				u.ID, u.Alias, u.URL, redirectCode(u.RedirectCode), formatTime(u.CreatedAt), formatTime(u.ExpiresAt), formatID(u.OwnerID))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "%d of %d links\n", len(list), total)
+	case "stats":
+		fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		days := fs.Int("days", 30, "days of daily counts")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 1 || *days <= 0 {
+			return errors.New(linkUsage)
+		}
+		alias := policy.Normalize(fs.Arg(0))
+
+		since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(*days - 1))
+		st, err := links.ClickStats(ctx, alias, since)
+		if err != nil {
+			if errors.Is(err, storage.ErrURLNotFound) {
+				return fmt.Errorf("no link %q", alias)
+			}
+			return err
+		}
+
+		fmt.Fprintf(out, "%s: %d clicks in total\n", alias, st.Total)
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DAY\tCLICKS")
+		for _, d := range st.Daily {
+			fmt.Fprintf(w, "%s\t%d\n", d.Day, d.Count)
+		}
+		return w.Flush()
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		file := fs.String("file", "-", "output file")
+		format := fs.String("format", "", "jsonl or csv, by the file extension when empty")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 {
+			return errors.New(linkUsage)
+		}
+
+		var f *os.File
+		w := out
+		if *file != "-" {
+			var err error
+			if f, err = os.Create(*file); err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+
+		tw, err := transfer.NewWriter(w, fileFormat(*format, *file))
+		if err != nil {
+			return err
+		}
+
+		n, err := transfer.Export(ctx, links, tw)
+		if err != nil {
+			return err
+		}
+
+		if f != nil {
+			if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "exported %d links to %s\n", n, *file)
+		}
+	case "import":
+		fs := flag.NewFlagSet("import", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		file := fs.String("file", "-", "input file")
+		format := fs.String("format", "", "jsonl, csv or a vendor format, by the file extension when empty")
+		onConflict := fs.String("on-conflict", transfer.ConflictSkip, "skip, overwrite or rename")
+		owner := fs.String("owner", "", "user name of the owner of every link, existing owners from the file when empty")
+		quiet := fs.Bool("quiet", false, "print only the summary")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 0 {
+			return errors.New(linkUsage)
+		}
+
+		ownerID, err := lookupOwner(ctx, users, *owner)
+		if err != nil {
+			return err
+		}
+
+		importer, err := transfer.NewImporter(links, aliases, policy, *onConflict, ownerID)
+		if err != nil {
+			return err
+		}
+
+		r := in
+		if *file != "-" {
+			f, err := os.Open(*file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		tr, err := transfer.NewReader(r, fileFormat(*format, *file))
+		if err != nil {
+			return err
+		}
+
+		// созданные без замечаний ссылки не печатаются, их может быть очень много
+		summary, err := importer.Import(ctx, tr, func(ev transfer.Event) {
+			if *quiet || (ev.Result == transfer.ResultCreated && ev.Message == "") {
+				return
+			}
+			fmt.Fprintf(out, "line %d: %s %s", ev.Line, ev.Result, ev.Alias)
+			if ev.Message != "" {
+				fmt.Fprintf(out, ": %s", ev.Message)
+			}
+			fmt.Fprintln(out)
+		})
+
+		fmt.Fprintf(out, "read %d links: %d created, %d overwritten, %d renamed, %d skipped, %d invalid\n",
+			summary.Total(), summary.Created, summary.Overwritten, summary.Renamed, summary.Skipped, summary.Invalid)
+
+		return err
+	default:
+		return errors.New(linkUsage)
+	}
+
+	return nil
+}
+
+// createLink validates the request with the rules of POST /url and saves it
+func createLink(ctx context.Context, links storage.URLStorage, validate *validator.Validate, aliases save.AliasGenerator, policy save.AliasPolicy, req save.Request, ownerID int64) (storage.URL, error) {
+	req.Alias = policy.Normalize(req.Alias)
+
+	if err := validate.Struct(req); err != nil {
+		var validateErr validator.ValidationErrors
+		if errors.As(err, &validateErr) {
+			return storage.URL{}, errors.New(resp.ValidationError(validateErr).Error)
+		}
+		return storage.URL{}, err
+	}
+
+	expiresAt, fieldErr := req.Expiry(time.Now())
+	if fieldErr != nil {
+		return storage.URL{}, errors.New(fieldErr.Message)
+	}
+
+	u := storage.URL{
+		Alias:        req.Alias,
+		URL:          req.URL,
+		RedirectCode: req.RedirectCode,
+		ExpiresAt:    expiresAt,
+		OwnerID:      ownerID,
+	}
+
+	const maxRetries = 4
+	for attempt := 0; ; attempt++ {
+		generated := req.Alias == ""
+		if generated {
+			alias, err := save.GenerateAlias(aliases, policy)
+			if err != nil {
+				return storage.URL{}, err
+			}
+			u.Alias = alias
+		}
+
+		id, err := links.SaveURL(ctx, u)
+		if errors.Is(err, storage.ErrURLExists) {
+			if generated && attempt < maxRetries {
+				continue
+			}
+			return storage.URL{}, fmt.Errorf("alias %q is taken", u.Alias)
+		}
+		if err != nil {
+			return storage.URL{}, err
+		}
+
+		u.ID = id
+		return u, nil
+	}
+}
+
+// lookupOwner returns id of the user with the name, zero for an empty name
+func lookupOwner(ctx context.Context, users userManager, name string) (int64, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	user, err := users.GetUserByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return 0, fmt.Errorf("no user %q", name)
+		}
+		return 0, err
+	}
+
+	return user.ID, nil
+}
+
+// fileFormat is the format flag or the one of the file extension
+func fileFormat(format, file string) string {
+	if format != "" {
+		return format
+	}
+	if strings.EqualFold(filepath.Ext(file), ".csv") {
+		return transfer.FormatCSV
+	}
+	return transfer.FormatJSONL
+}
+
+func redirectCode(code int) string {
+	if code == 0 {
+		return "default"
+	}
+	return strconv.Itoa(code)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatID(id int64) string {
+	if id == 0 {
+		return "-"
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"url-shortener/internal/config1"
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// aliasesStub hands out the aliases in order
+type aliasesStub struct {
+	aliases []string
+	calls   int
+}
+
+func (a *aliasesStub) Generate() (string, error) {
+	if a.calls >= len(a.aliases) {
+		return "", errors.New("no more aliases")
+	}
+	a.calls++
+	return a.aliases[a.calls-1], nil
+}
+
+func testPolicy() *alias.Policy {
+	return alias.NewPolicy(alias.Rules{
+		MinLength: 3,
+		MaxLength: 64,
+		Charset:   "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_",
+	})
+}
+
+func TestCreateLink(t *testing.T) {
+	cases := []struct {
+		name    string
+		alias   string
+		taken   []string
+		aliases []string
+		// wantAlias is empty when an error is expected
+		wantAlias string
+		wantCalls int
+	}{
+		{
+			name:      "Generated",
+			aliases:   []string{"abc123"},
+			wantAlias: "abc123",
+			wantCalls: 1,
+		},
+		{
+			name:      "Retries taken generated aliases",
+			taken:     []string{"taken1", "taken2"},
+			aliases:   []string{"taken1", "taken2", "fresh1"},
+			wantAlias: "fresh1",
+			wantCalls: 3,
+		},
+		{
+			name:      "Gives up after max retries",
+			taken:     []string{"taken1"},
+			aliases:   []string{"taken1", "taken1", "taken1", "taken1", "taken1", "fresh1"},
+			wantCalls: 5,
+		},
+		{
+			name:      "Custom alias is not retried",
+			alias:     "taken1",
+			taken:     []string{"taken1"},
+			aliases:   []string{"fresh1"},
+			wantCalls: 0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			store := memory.New()
+			for _, a := range tc.taken {
+				_, err := store.SaveURL(ctx, storage.URL{Alias: a, URL: "https://old.example"})
+				require.NoError(t, err)
+			}
+
+			aliases := &aliasesStub{aliases: tc.aliases}
+			policy := testPolicy()
+
+			u, err := createLink(ctx, store, save.NewValidator(policy), aliases, policy,
+				save.Request{URL: "https://new.example", Alias: tc.alias}, 7)
+			assert.Equal(t, tc.wantCalls, aliases.calls)
+
+			if tc.wantAlias == "" {
+				assert.ErrorContains(t, err, "is taken")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantAlias, u.Alias)
+			assert.Equal(t, int64(7), u.OwnerID)
+
+			got, err := store.GetURL(ctx, tc.wantAlias)
+			require.NoError(t, err)
+			assert.Equal(t, u.ID, got.ID)
+			assert.Equal(t, "https://new.example", got.URL)
+		})
+	}
+}
+
+// usersStub fails every lookup
+type usersStub struct {
+	userManager
+	err error
+}
+
+func (u usersStub) GetUserByName(ctx context.Context, name string) (storage.User, error) {
+	return storage.User{}, u.err
+}
+
+func TestLookupOwner(t *testing.T) {
+	ctx := context.Background()
+
+	store := memory.New()
+	id, err := store.SaveUser(ctx, storage.User{Name: "alice", Role: storage.RoleEditor})
+	require.NoError(t, err)
+
+	got, err := lookupOwner(ctx, store, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, id, got)
+
+	// без имени ссылка остаётся без владельца
+	got, err = lookupOwner(ctx, store, "")
+	require.NoError(t, err)
+	assert.Zero(t, got)
+
+	_, err = lookupOwner(ctx, store, "bob")
+	assert.ErrorContains(t, err, `no user "bob"`)
+
+	diskErr := errors.New("disk I/O error")
+	_, err = lookupOwner(ctx, usersStub{err: diskErr}, "alice")
+	assert.ErrorIs(t, err, diskErr)
+}
+
+func TestRunLinkReservesServerRoutes(t *testing.T) {
+	var cfg config1.Config
+	require.NoError(t, cleanenv.ReadEnv(&cfg))
+	cfg.StorageType = config1.StorageSQLite
+	cfg.StoragePath = filepath.Join(t.TempDir(), "storage.db")
+
+	for _, route := range []string{"url", "healthz", "readyz", "metrics"} {
+		var out bytes.Buffer
+		err := runLink(&cfg, strings.NewReader(""), &out, []string{"create", "-url", "https://example.com", "-alias", route})
+		assert.ErrorContains(t, err, "reserved", "alias %q", route)
+	}
+
+	var out bytes.Buffer
+	err := runLink(&cfg, strings.NewReader(""), &out, []string{"create", "-url", "https://example.com", "-alias", "mine"})
+	require.NoError(t, err)
+}
+
+func TestLinkCommandFoldsAlias(t *testing.T) {
+	ctx := context.Background()
+
+	store := memory.New()
+	policy := alias.NewPolicy(alias.Rules{MinLength: 3, MaxLength: 64, FoldCase: true})
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := linkCommand(ctx, store, store, &aliasesStub{}, policy, strings.NewReader(""), &out, args)
+		return out.String(), err
+	}
+
+	out, err := run("create", "-url", "https://example.com", "-alias", "MyLink")
+	require.NoError(t, err)
+	assert.Contains(t, out, "mylink -> https://example.com")
+
+	out, err = run("get", "MYLINK")
+	require.NoError(t, err)
+	assert.Contains(t, out, "mylink")
+
+	out, err = run("stats", "MyLink")
+	require.NoError(t, err)
+	assert.Contains(t, out, "mylink: 0 clicks")
+
+	out, err = run("delete", "MyLink")
+	require.NoError(t, err)
+	assert.Equal(t, "deleted link mylink\n", out)
+
+	_, err = store.GetURL(ctx, "mylink")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
@@ -4,20 +4,59 @@ package main
 // go run .\cmd\url-shortener
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"url-shortener/internal/clicks"
 	"url-shortener/internal/config1"
+	"url-shortener/internal/http-server/handlers/health"
+	"url-shortener/internal/http-server/handlers/redirect"
+	"url-shortener/internal/http-server/handlers/url/batch"
 	"url-shortener/internal/http-server/handlers/url/delete"
+	"url-shortener/internal/http-server/handlers/url/get"
+	"url-shortener/internal/http-server/handlers/url/list"
+	"url-shortener/internal/http-server/handlers/url/purge"
 	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/stats"
+	"url-shortener/internal/http-server/handlers/url/update"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/http-server/middleware/foldalias"
 	"url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/http-server/middleware/ratelimit"
+	"url-shortener/internal/http-server/middleware/tracing"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/buildinfo"
 	"url-shortener/internal/lib/logger/handlers/slogpretty"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/cache"
+	"url-shortener/internal/storage/instrument"
+	"url-shortener/internal/storage/memory"
+	"url-shortener/internal/storage/postgres"
 	"url-shortener/internal/storage/sqlite"
+	"url-shortener/internal/storage/timeout"
+	"url-shortener/internal/sweeper"
+	"url-shortener/internal/transfer"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -28,18 +67,57 @@ const (
 
 func main() {
 	cfg := config1.MustLoad()
+
+	// go run ./cmd/url-shortener migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Stdout, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// go run ./cmd/url-shortener user create|list
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		if err := runUser(cfg, os.Stdout, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// go run ./cmd/url-shortener apikey create|list|revoke
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := runAPIKey(cfg, os.Stdout, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// go run ./cmd/url-shortener link create|get|delete|list|stats|export|import
+	if len(os.Args) > 1 && os.Args[1] == "link" {
+		if err := runLink(cfg, os.Stdin, os.Stdout, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	build := buildinfo.Get()
+
 	log := setupLogger(cfg.Env)
 	log.Info(
 		"starting url-shortener",
 		slog.String("env", cfg.Env),
-		slog.String("version", "123"),
+		slog.String("version", build.Version),
+		slog.String("commit", build.Commit),
 	)
 	log.Debug("debug messages are enabled")
 
 	// fmt.Printf("Env=%s\nStorage=%s\nHTTP=%+v\n", cfg.Env, cfg.StoragePath, cfg.HTTPServer)
 
-	// TODO: init storage: sqlite
-	storage, err := sqlite.New(cfg.StoragePath)
+	store, err := setupStorage(cfg)
 	if err != nil {
 		log.Error("failed to init storage", sl.Err(err))
 		os.Exit(1)
@@ -60,29 +138,122 @@ func main() {
 	// 	os.Exit(1)
 	// }
 
-	_ = storage
+	if cfg.Auth.BootstrapKey != "" {
+		key, created, err := bootstrapAdmin(context.Background(), store, store, cfg.Auth.BootstrapKey)
+		if err != nil {
+			log.Error("failed to create bootstrap api key", sl.Err(err))
+			os.Exit(1)
+		}
+		switch {
+		case created:
+			log.Info("bootstrap api key created", slog.Int64("key_id", key.ID))
+		case key.Revoked():
+			log.Warn("bootstrap api key is revoked", slog.Int64("key_id", key.ID))
+		}
+	}
 
-	// TODO: init router: chi, "chi render"
-	router := chi.NewRouter()
+	tracerProvider, err := setupTracing(cfg, build)
+	if err != nil {
+		log.Error("failed to init tracing", sl.Err(err))
+		os.Exit(1)
+	}
 
-	// middleware
-	router.Use(middleware.RequestID)
-	router.Use(middleware.Logger)
-	router.Use(logger.New(log))
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
+	// метрики и трассировка включаются в конфиге, интерфейсы остаются nil, если они выключены
+	var (
+		promMetrics   = setupMetrics(cfg)
+		queryObserver instrument.Observer
+		storageTracer trace.Tracer
+	)
+	if promMetrics != nil {
+		queryObserver = promMetrics
+	}
+	if tracerProvider != nil {
+		storageTracer = tracerProvider.Tracer("url-shortener/storage")
+	}
+
+	// все обращения сервера к хранилищу идут через instrumented
+	var instrumented instrument.Backend = store
+	if queryObserver != nil || storageTracer != nil {
+		instrumented = instrument.New(store, queryObserver, storageTracer)
+	}
+
+	// операции из обработчиков запросов и запись переходов ограничены по времени
+	bounded := timeout.NewBackend(instrumented, timeout.Timeouts{
+		Get:    cfg.StorageTimeouts.Get,
+		Save:   cfg.StorageTimeouts.Save,
+		Delete: cfg.StorageTimeouts.Delete,
+		List:   cfg.StorageTimeouts.List,
+		Update: cfg.StorageTimeouts.Update,
+		Batch:  cfg.StorageTimeouts.Batch,
+		Auth:   cfg.StorageTimeouts.Auth,
+		Stats:  cfg.StorageTimeouts.Stats,
+		Clicks: cfg.StorageTimeouts.Clicks,
+	})
+	var urlStorage storage.URLStorage = bounded
+
+	var urlCache *cache.Storage
+	if cfg.Cache.Size > 0 {
+		urlCache = cache.New(urlStorage, cfg.Cache.Size, cfg.Cache.TTL, cfg.Cache.NegativeTTL)
+		urlStorage = urlCache
+	}
+
+	aliases, err := setupAliasGenerator(log, cfg, store)
+	if err != nil {
+		log.Error("failed to init alias generator", sl.Err(err))
+		os.Exit(1)
+	}
 
-	router.Route("/url", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("url-shortener", map[string]string{
-			cfg.HTTPServer.User: cfg.HTTPServer.Password,
-		}))
+	if promMetrics != nil {
+		promMetrics.WatchAliases(aliases)
+		if urlCache != nil {
+			promMetrics.WatchCache(urlCache)
+		}
+	}
 
-		r.Post("/", save.New(log, storage))
-		// TODO: add DELETE /url/{id}
-		r.Delete("/{id}", delete.New(log, storage))
+	aliasPolicy, err := setupAliasPolicy(cfg)
+	if err != nil {
+		log.Error("failed to init alias policy", sl.Err(err))
+		os.Exit(1)
+	}
 
+	clickRecorder := clicks.NewRecorder(log, bounded, cfg.Clicks.BufferSize, cfg.Clicks.BatchSize, cfg.Clicks.FlushInterval)
+
+	var invalidator purge.CacheInvalidator
+	if urlCache != nil {
+		invalidator = urlCache
+	}
+
+	router := setupRouter(log, routerDeps{
+		cfg:            cfg,
+		build:          build,
+		store:          bounded,
+		urls:           urlStorage,
+		aliases:        aliases,
+		policy:         aliasPolicy,
+		clicks:         clickRecorder,
+		cache:          invalidator,
+		metrics:        promMetrics,
+		tracerProvider: tracerProvider,
 	})
 
+	recorderCtx, stopRecorder := context.WithCancel(context.Background())
+	recorderDone := make(chan struct{})
+	go func() {
+		defer close(recorderDone)
+		clickRecorder.Run(recorderCtx)
+	}()
+
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	sweeperDone := make(chan struct{})
+	go func() {
+		defer close(sweeperDone)
+		if cfg.Sweeper.Interval <= 0 {
+			log.Info("sweeper disabled")
+			return
+		}
+		sweeper.New(log, instrumented, cfg.Sweeper.Interval, cfg.Sweeper.Archive).Run(sweeperCtx)
+	}()
+
 	srv := &http.Server{
 		Addr:         cfg.HTTPServer.Address,
 		Handler:      router,
@@ -91,13 +262,335 @@ func main() {
 		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
 	}
 
-	// TODO: run server
-	if err := srv.ListenAndServe(); err != nil {
+	// сервер работает до SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Info("starting server", slog.String("address", cfg.HTTPServer.Address))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		log.Info("stopping server")
+	case err := <-serverErr:
 		log.Error("failed to start server", sl.Err(err))
+		exitCode = 1
+	}
+
+	// перестаём принимать соединения и ждём завершения текущих запросов
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("failed to stop server gracefully", sl.Err(err))
+		exitCode = 1
 	}
 
-	log.Error("server stopped")
+	stopSweeper()
+	<-sweeperDone
+
+	// запросов больше нет, записываем оставшиеся в буфере переходы
+	stopRecorder()
+	<-recorderDone
+
+	aliasStats := aliases.Stats()
+	log.Info("alias generator stats",
+		slog.Int("length", aliasStats.Length),
+		slog.Int64("collisions", aliasStats.Collisions),
+		slog.Int64("saturations", aliasStats.Saturations),
+	)
+
+	if urlCache != nil {
+		cacheStats := urlCache.Stats()
+		log.Info("url cache stats", slog.Int64("hits", cacheStats.Hits), slog.Int64("misses", cacheStats.Misses))
+	}
+
+	// отправляем спаны, которые ещё не ушли в экспортер
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+			log.Error("failed to flush spans", sl.Err(err))
+		}
+	}
+
+	if err := store.Close(); err != nil {
+		log.Error("failed to close storage", sl.Err(err))
+		exitCode = 1
+	}
+
+	log.Info("server stopped")
+
+	os.Exit(exitCode)
+}
+
+// routerDeps is what the routes of the server are built from
+type routerDeps struct {
+	cfg   *config1.Config
+	build buildinfo.Info
+	// store serves auth, stats and health checks, it is wrapped with timeouts
+	store instrument.Backend
+	// urls serves the link handlers, it is wrapped with timeouts and the cache
+	urls    storage.URLStorage
+	aliases *alias.Adaptive
+	policy  *alias.Policy
+	clicks  redirect.ClickRecorder
+	// cache, metrics and tracerProvider are nil when disabled
+	cache          purge.CacheInvalidator
+	metrics        *metrics.Metrics
+	tracerProvider *sdktrace.TracerProvider
+}
+
+// first segments of the server routes, aliases may not take them
+const (
+	routeURL     = "url"
+	routeHealthz = "healthz"
+	routeReadyz  = "readyz"
+	routeMetrics = "metrics"
+)
+
+// routeSegments are the first static segments of every route of setupRouter.
+// metrics is there even when disabled, so enabling it later hides no link.
+func routeSegments() []string {
+	return []string{routeURL, routeHealthz, routeReadyz, routeMetrics}
+}
+
+// setupRouter registers the routes of the server. Their first segments
+// are reserved by setupAliasPolicy, see routeSegments.
+func setupRouter(log *slog.Logger, d routerDeps) *chi.Mux {
+	var (
+		requestObserver logger.RequestObserver
+		redirectCounter redirect.RedirectCounter
+	)
+	if d.metrics != nil {
+		requestObserver = d.metrics
+		redirectCounter = d.metrics
+	}
+
+	// rateLimit returns the limiter middleware, or nothing when the policy is disabled
+	rateLimit := func(name string, p config1.RateLimitPolicy) []func(http.Handler) http.Handler {
+		policy := ratelimit.Policy{Requests: p.Requests, Per: p.Per, Burst: p.Burst}
+		if !policy.Enabled() {
+			log.Info("rate limit disabled", slog.String("limiter", name))
+			return nil
+		}
+		return []func(http.Handler) http.Handler{ratelimit.New(name, policy).Handler(log)}
+	}
+
+	// алиасы ищутся в том же регистре, в каком сохраняются
+	fold := foldalias.New(d.policy)
+
+	// TODO: init router: chi, "chi render"
+	router := chi.NewRouter()
+
+	// middleware
+	router.Use(middleware.RequestID)
+	router.Use(middleware.Logger)
+	if d.tracerProvider != nil {
+		router.Use(tracing.New(d.tracerProvider.Tracer("url-shortener/http"), otel.GetTextMapPropagator()))
+	}
+	router.Use(logger.New(log, requestObserver))
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.URLFormat)
+
+	router.Route("/"+routeURL, func(r chi.Router) {
+		// ключи создаются командой `url-shortener apikey create` или из auth.bootstrap_key
+		r.Use(auth.New(log, d.store))
+
+		// роль пользователя определяет, что он может делать, а scope ключа
+		// может дополнительно сузить права конкретного ключа
+		read := r.With(auth.RequireRole(storage.RoleViewer), auth.RequireScope(apikey.ScopeRead))
+		write := r.With(auth.RequireRole(storage.RoleEditor), auth.RequireScope(apikey.ScopeWrite))
+		remove := r.With(auth.RequireRole(storage.RoleEditor), auth.RequireScope(apikey.ScopeDelete))
+
+		// общий лимит на создание ссылок, пакет расходует по токену на каждую ссылку
+		saveLimit := rateLimit("save", d.cfg.RateLimit.Save)
+		write.With(saveLimit...).Post("/", save.New(log, d.urls, d.aliases, d.policy, d.cfg.Save.ReuseExisting))
+		write.With(saveLimit...).Post("/batch", batch.New(log, d.urls, d.aliases, d.policy, d.cfg.Save.BatchMaxItems))
+		read.Get("/", list.New(log, d.urls))
+		read.With(fold).Get("/{alias}", get.New(log, d.urls))
+		write.With(fold).Patch("/{alias}", update.New(log, d.urls))
+		remove.With(fold).Delete("/{alias}", delete.New(log, d.urls))
+		read.With(fold).Get("/{alias}/stats", stats.New(log, d.store))
+
+		// сброс кэша после изменений командой `url-shortener link`
+		if d.cache != nil {
+			admin := r.With(auth.RequireRole(storage.RoleAdmin), auth.RequireScope(apikey.ScopeDelete))
+			admin.With(fold).Delete("/{alias}/cache", purge.New(log, d.cache))
+		}
+
+	})
+
+	// пробы оркестратора, без авторизации и лимитов
+	router.Get("/"+routeHealthz, health.Live())
+	router.Get("/"+routeReadyz, health.Ready(log, d.store, d.cfg.Health.ReadyTimeout, d.build))
+
+	if d.metrics != nil {
+		router.Get("/"+routeMetrics, d.metrics.Handler().ServeHTTP)
+	}
+
+	router.With(rateLimit("redirect", d.cfg.RateLimit.Redirect)...).With(fold).Get("/{alias}", redirect.New(log, d.urls, d.clicks, redirectCounter, d.cfg.Redirect.StatusCode))
+
+	return router
+}
+
+// setupMetrics returns nil when metrics are disabled
+func setupMetrics(cfg *config1.Config) *metrics.Metrics {
+	if !cfg.Metrics.Enabled {
+		return nil
+	}
+	return metrics.New()
+}
+
+// backend is implemented by every storage that can be chosen in the config
+type backend interface {
+	storage.URLStorage
+	sweeper.ExpiredRemover
+	clicks.ClickSaver
+	stats.ClickStatsGetter
+	auth.KeyGetter
+	health.Checker
+	apiKeyManager
+	userManager
+	transfer.URLSaver
+	io.Closer
+}
+
+// выбор хранилища по storage_type из конфига
+func setupStorage(cfg *config1.Config) (backend, error) {
+	switch cfg.StorageType {
+	case config1.StoragePostgres:
+		return postgres.New(cfg.Postgres.DSN)
+	case config1.StorageMemory:
+		return memory.New(), nil
+	default:
+		return sqlite.New(cfg.StoragePath)
+	}
+}
+
+// выбор генератора алиасов по alias.generator из конфига; длина алиасов
+// растёт, когда коллизии становятся частыми
+func setupAliasGenerator(log *slog.Logger, cfg *config1.Config, store storage.URLStorage) (*alias.Adaptive, error) {
+	const op = "main.setupAliasGenerator"
+
+	switch cfg.Alias.Generator {
+	case config1.AliasSequential, config1.AliasHashids:
+		// счётчик продолжается с последнего id в хранилище
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.StorageTimeouts.List)
+		defer cancel()
+
+		last, _, err := store.ListURLs(ctx, storage.ListParams{Limit: 1, SortBy: storage.SortByID, Desc: true})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		var lastID uint64
+		if len(last) > 0 {
+			lastID = uint64(last[0].ID)
+		}
+
+		counter := alias.NewCounter(lastID)
+		if cfg.Alias.Generator == config1.AliasHashids {
+			return alias.NewAdaptive(log, cfg.Alias.Length, cfg.Alias.MaxLength, func(length int) alias.Generator {
+				return alias.NewHashids(counter, cfg.Alias.Salt, length)
+			}), nil
+		}
+		return alias.NewAdaptive(log, cfg.Alias.Length, cfg.Alias.MaxLength, func(length int) alias.Generator {
+			return alias.NewSequential(counter, length)
+		}), nil
+	case config1.AliasWords:
+		return alias.NewAdaptive(log, cfg.Alias.Words, cfg.Alias.MaxWords, func(words int) alias.Generator {
+			return alias.NewWords(words)
+		}), nil
+	default:
+		return alias.NewAdaptive(log, cfg.Alias.Length, cfg.Alias.MaxLength, func(length int) alias.Generator {
+			return alias.NewRandom(length)
+		}), nil
+	}
+}
+
+func setupAliasPolicy(cfg *config1.Config) (*alias.Policy, error) {
+	const op = "main.setupAliasPolicy"
+
+	rules := cfg.Alias.Custom
+
+	var blocked []string
+	if rules.BlocklistFile != "" {
+		words, err := alias.LoadBlocklist(rules.BlocklistFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		blocked = words
+	}
+
+	policy := alias.NewPolicy(alias.Rules{
+		MinLength: rules.MinLength,
+		MaxLength: rules.MaxLength,
+		Charset:   rules.Charset,
+		FoldCase:  rules.CaseFolding == "lower",
+		Reserved:  rules.Reserved,
+		Blocked:   blocked,
+	})
+	// алиасы не должны совпадать с маршрутами
+	reserveRoutes(policy)
+
+	return policy, nil
+}
+
+// setupTracing installs the global tracer provider, nil when tracing is disabled
+func setupTracing(cfg *config1.Config, build buildinfo.Info) (*sdktrace.TracerProvider, error) {
+	const op = "main.setupTracing"
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch cfg.Tracing.Exporter {
+	case config1.TracingNone:
+		return nil, nil
+	case config1.TracingStdout:
+		exporter, err = stdouttrace.New()
+	case config1.TracingOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint)}
+		if cfg.Tracing.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	default:
+		return nil, fmt.Errorf("%s: unknown exporter %q", op, cfg.Tracing.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("url-shortener"),
+		semconv.ServiceVersion(build.Version),
+		semconv.DeploymentEnvironment(cfg.Env),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return tp, nil
+}
 
+// reserveRoutes reserves the first static segment of every route, e.g. url for /url/{alias}.
+// The server and the link subcommand share it, so they reject the same aliases.
+func reserveRoutes(policy *alias.Policy) {
+	policy.Reserve(routeSegments()...)
 }
 
 // конфигурация логгера
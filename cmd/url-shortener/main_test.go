@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"url-shortener/internal/config1"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/metrics"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routeSegments must list the first segment of every registered route,
+// otherwise the link subcommand would accept an alias the server can't serve
+func TestRouteSegments(t *testing.T) {
+	var cfg config1.Config
+	require.NoError(t, cleanenv.ReadEnv(&cfg))
+
+	store := memory.New()
+	router := setupRouter(slogdiscard.NewDiscardLogger(), routerDeps{
+		cfg:     &cfg,
+		store:   store,
+		urls:    store,
+		policy:  alias.NewPolicy(alias.Rules{}),
+		cache:   cacheStub{},
+		metrics: metrics.New(),
+	})
+
+	registered := make(map[string]bool)
+	err := chi.Walk(router, func(_, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if segment != "" && !strings.ContainsAny(segment, "{*") {
+			registered[segment] = true
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for segment := range registered {
+		assert.Contains(t, routeSegments(), segment)
+	}
+	for _, segment := range routeSegments() {
+		assert.True(t, registered[segment], "segment %q is not routed", segment)
+	}
+}
+
+type cacheStub struct{}
+
+func (cacheStub) Invalidate(string) {}
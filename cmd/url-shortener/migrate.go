@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"url-shortener/internal/config1"
+	"url-shortener/internal/storage/migrate"
+	"url-shortener/internal/storage/postgres"
+	"url-shortener/internal/storage/sqlite"
+)
+
+const migrateUsage = "usage: url-shortener migrate up|down|status"
+
+// runMigrate handles `url-shortener migrate <command>`
+func runMigrate(cfg *config1.Config, out io.Writer, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	m, err := setupMigrator(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		n, err := m.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "applied %d migration(s)\n", n)
+	case "down":
+		mig, err := m.Down()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "rolled back %04d_%s\n", mig.Version, mig.Name)
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, st := range statuses {
+			appliedAt := "pending"
+			if st.Applied {
+				appliedAt = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", st.Version, st.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// setupMigrator opens the configured database without applying migrations
+func setupMigrator(cfg *config1.Config) (*migrate.Migrator, error) {
+	switch cfg.StorageType {
+	case config1.StorageSQLite:
+		s, err := sqlite.Open(cfg.StoragePath)
+		if err != nil {
+			return nil, err
+		}
+		return s.Migrator()
+	case config1.StoragePostgres:
+		s, err := postgres.Open(cfg.Postgres.DSN)
+		if err != nil {
+			return nil, err
+		}
+		return s.Migrator()
+	default:
+		return nil, fmt.Errorf("storage %q has no migrations", cfg.StorageType)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"url-shortener/internal/config1"
+	"url-shortener/internal/storage"
+)
+
+const userUsage = `usage:
+  url-shortener user create -name <name> [-role viewer|editor|admin]
+  url-shortener user set-role -name <name> -role viewer|editor|admin
+  url-shortener user list`
+
+// userManager is what the user subcommand needs from the storage
+type userManager interface {
+	SaveUser(ctx context.Context, u storage.User) (int64, error)
+	GetUserByName(ctx context.Context, name string) (storage.User, error)
+	SetUserRole(ctx context.Context, id int64, role string) error
+	ListUsers(ctx context.Context) ([]storage.User, error)
+}
+
+// runUser handles `url-shortener user <command>`
+func runUser(cfg *config1.Config, out io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(userUsage)
+	}
+	if cfg.StorageType == config1.StorageMemory {
+		return errors.New("users of the memory storage live only inside the running server, set auth.bootstrap_key to get an admin key")
+	}
+
+	store, err := setupStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	return userCommand(context.Background(), store, out, args)
+}
+
+func userCommand(ctx context.Context, users userManager, out io.Writer, args []string) error {
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("create", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		name := fs.String("name", "", "user name")
+		role := fs.String("role", storage.RoleViewer, "viewer, editor or admin")
+		if err := fs.Parse(args[1:]); err != nil || *name == "" || fs.NArg() != 0 {
+			return errors.New(userUsage)
+		}
+		if !storage.ValidRole(*role) {
+			return fmt.Errorf("unknown role %q, expected %s", *role, strings.Join(storage.Roles, ", "))
+		}
+
+		id, err := users.SaveUser(ctx, storage.User{Name: *name, Role: *role})
+		if err != nil {
+			if errors.Is(err, storage.ErrUserExists) {
+				return fmt.Errorf("user %q already exists", *name)
+			}
+			return err
+		}
+
+		fmt.Fprintf(out, "created user %d (%s) with role %s\n", id, *name, *role)
+	case "set-role":
+		fs := flag.NewFlagSet("set-role", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		name := fs.String("name", "", "user name")
+		role := fs.String("role", "", "viewer, editor or admin")
+		if err := fs.Parse(args[1:]); err != nil || *name == "" || *role == "" || fs.NArg() != 0 {
+			return errors.New(userUsage)
+		}
+		if !storage.ValidRole(*role) {
+			return fmt.Errorf("unknown role %q, expected %s", *role, strings.Join(storage.Roles, ", "))
+		}
+
+		user, err := users.GetUserByName(ctx, *name)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				return fmt.Errorf("no user %q", *name)
+			}
+			return err
+		}
+
+		if err := users.SetUserRole(ctx, user.ID, *role); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "user %s now has role %s\n", user.Name, *role)
+	case "list":
+		if len(args) != 1 {
+			return errors.New(userUsage)
+		}
+
+		list, err := users.ListUsers(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tROLE\tCREATED AT")
+		for _, u := range list {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", u.ID, u.Name, u.Role, u.CreatedAt.Format(time.RFC3339))
+		}
+		return w.Flush()
+	default:
+		return errors.New(userUsage)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+// Package clicks records redirect events without blocking the redirect itself.
+package clicks
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// ClickSaver is implemented by storages that keep click events.
+type ClickSaver interface {
+	SaveClicks(ctx context.Context, clicks []storage.Click) error
+}
+
+// Recorder buffers clicks in a channel and writes them in batches.
+// Clicks that do not fit into the buffer are dropped, so a slow
+// storage never delays redirects.
+type Recorder struct {
+	log           *slog.Logger
+	saver         ClickSaver
+	events        chan storage.Click
+	batchSize     int
+	flushInterval time.Duration
+	dropped       atomic.Int64
+}
+
+func NewRecorder(log *slog.Logger, saver ClickSaver, bufferSize, batchSize int, flushInterval time.Duration) *Recorder {
+	return &Recorder{
+		log:           log.With(slog.String("component", "clicks/recorder")),
+		saver:         saver,
+		events:        make(chan storage.Click, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+// Record queues the click and returns immediately.
+func (r *Recorder) Record(c storage.Click) {
+	select {
+	case r.events <- c:
+	default:
+		if n := r.dropped.Add(1); n == 1 || n%1000 == 0 {
+			r.log.Warn("click buffer is full, dropping clicks", slog.Int64("dropped", n))
+		}
+	}
+}
+
+// Dropped returns how many clicks were lost because the buffer was full.
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Run writes queued clicks until ctx is cancelled, then flushes
+// whatever is left in the buffer and returns.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	// clicks left in the buffer are still written after ctx is cancelled
+	writeCtx := context.WithoutCancel(ctx)
+
+	batch := make([]storage.Click, 0, r.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.saver.SaveClicks(writeCtx, batch); err != nil {
+			r.log.Error("failed to save clicks", sl.Err(err), slog.Int("count", len(batch)))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case c := <-r.events:
+			batch = append(batch, c)
+			if len(batch) >= r.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case c := <-r.events:
+					batch = append(batch, c)
+					if len(batch) >= r.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					r.log.Info("click recorder stopped")
+					return
+				}
+			}
+		}
+	}
+}
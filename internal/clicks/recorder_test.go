@@ -0,0 +1,87 @@
+package clicks
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type saverStub struct {
+	mu      sync.Mutex
+	batches [][]storage.Click
+}
+
+func (s *saverStub) SaveClicks(_ context.Context, clicks []storage.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, append([]storage.Click(nil), clicks...))
+
+	return nil
+}
+
+func (s *saverStub) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, b := range s.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func TestRecorderBatchesAndFlushesOnStop(t *testing.T) {
+	saver := &saverStub{}
+	rec := NewRecorder(slogdiscard.NewDiscardLogger(), saver, 100, 10, time.Hour)
+
+	for i := 0; i < 25; i++ {
+		rec.Record(storage.Click{URLID: 1, ClickedAt: time.Now()})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rec.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool { return saver.total() >= 20 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+
+	assert.Equal(t, 25, saver.total())
+	for _, b := range saver.batches {
+		assert.LessOrEqual(t, len(b), 10)
+	}
+}
+
+func TestRecorderFlushesOnInterval(t *testing.T) {
+	saver := &saverStub{}
+	rec := NewRecorder(slogdiscard.NewDiscardLogger(), saver, 100, 10, 5*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rec.Run(ctx)
+
+	rec.Record(storage.Click{URLID: 1, ClickedAt: time.Now()})
+
+	assert.Eventually(t, func() bool { return saver.total() == 1 }, time.Second, time.Millisecond)
+}
+
+func TestRecorderDropsWhenBufferIsFull(t *testing.T) {
+	rec := NewRecorder(slogdiscard.NewDiscardLogger(), &saverStub{}, 2, 10, time.Hour)
+
+	for i := 0; i < 5; i++ {
+		rec.Record(storage.Click{URLID: 1})
+	}
+
+	assert.Equal(t, int64(3), rec.Dropped())
+}
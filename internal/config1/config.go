@@ -8,18 +8,193 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+// storage backends that can be chosen with storage_type
+const (
+	StorageSQLite   = "sqlite"
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+)
+
+// alias generators that can be chosen with alias.generator
+const (
+	AliasRandom     = "random"
+	AliasSequential = "sequential"
+	AliasHashids    = "hashids"
+	AliasWords      = "words"
+)
+
+// trace exporters that can be chosen with tracing.exporter
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+// MinBootstrapKeyLength keeps guessable keys out of auth.bootstrap_key
+const MinBootstrapKeyLength = 32
+
 type Config struct {
-	Env         string     `yaml:"env" env-default:"local"`
-	StoragePath string     `yaml:"storage_path" env-required:"true"`
-	HTTPServer  HTTPServer `yaml:"http_server"`
+	Env         string   `yaml:"env" env-default:"local"`
+	StorageType string   `yaml:"storage_type" env-default:"sqlite"`
+	StoragePath string   `yaml:"storage_path"`
+	Postgres    Postgres `yaml:"postgres"`
+	// StorageTimeouts limit single storage operations made while serving requests
+	StorageTimeouts StorageTimeouts `yaml:"storage_timeouts"`
+	HTTPServer      HTTPServer      `yaml:"http_server"`
+	Redirect        Redirect        `yaml:"redirect"`
+	Sweeper         Sweeper         `yaml:"sweeper"`
+	Clicks          Clicks          `yaml:"clicks"`
+	Cache           Cache           `yaml:"cache"`
+	RateLimit       RateLimit       `yaml:"rate_limit"`
+	Alias           Alias           `yaml:"alias"`
+	Save            Save            `yaml:"save"`
+	Metrics         Metrics         `yaml:"metrics"`
+	Health          Health          `yaml:"health"`
+	Tracing         Tracing         `yaml:"tracing"`
+	Auth            Auth            `yaml:"auth"`
+}
+
+type Postgres struct {
+	DSN string `yaml:"dsn" env:"POSTGRES_DSN"`
 }
 
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
-	User        string        `yaml:"user" env-default:"admin"`
-	Password    string        `yaml:"password" env-default:"admin"`
+	// ShutdownTimeout is how long in-flight requests may take after SIGINT/SIGTERM
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"`
+}
+
+type StorageTimeouts struct {
+	Get    time.Duration `yaml:"get" env-default:"1s"`
+	Save   time.Duration `yaml:"save" env-default:"2s"`
+	Delete time.Duration `yaml:"delete" env-default:"2s"`
+	List   time.Duration `yaml:"list" env-default:"3s"`
+	Update time.Duration `yaml:"update" env-default:"2s"`
+	// Batch limits saving a whole batch of links
+	Batch time.Duration `yaml:"batch" env-default:"10s"`
+	// Auth limits api key and user lookups of every authenticated request
+	Auth time.Duration `yaml:"auth" env-default:"1s"`
+	// Stats limits click stats aggregation, the heaviest query of the api
+	Stats time.Duration `yaml:"stats" env-default:"5s"`
+	// Clicks limits writing a batch of clicks
+	Clicks time.Duration `yaml:"clicks" env-default:"5s"`
+}
+
+type Redirect struct {
+	// StatusCode is used for links saved without their own redirect code
+	StatusCode int `yaml:"status_code" env-default:"302"`
+}
+
+type Sweeper struct {
+	// Interval between removals of expired links, zero disables the sweeper
+	Interval time.Duration `yaml:"interval" env-default:"1m"`
+	// Archive moves expired links to url_archive instead of deleting them
+	Archive bool `yaml:"archive" env-default:"false"`
+}
+
+type Clicks struct {
+	// BufferSize is how many clicks may wait for writing, extra clicks are dropped
+	BufferSize    int           `yaml:"buffer_size" env-default:"10000"`
+	BatchSize     int           `yaml:"batch_size" env-default:"100"`
+	FlushInterval time.Duration `yaml:"flush_interval" env-default:"1s"`
+}
+
+type Cache struct {
+	// Size is the max number of cached aliases, zero disables the cache
+	Size int `yaml:"size" env-default:"10000"`
+	// TTL also bounds how long changes made by the link subcommand stay
+	// unseen, DELETE /url/{alias}/cache drops an alias earlier
+	TTL         time.Duration `yaml:"ttl" env-default:"5m"`
+	NegativeTTL time.Duration `yaml:"negative_ttl" env-default:"10s"`
+}
+
+// RateLimit policies are per client: per api key for authenticated
+// requests and per remote IP for the others
+type RateLimit struct {
+	// Save limits link creation, POST /url/batch takes a token per link
+	Save RateLimitPolicy `yaml:"save"`
+	// Redirect limits following short links
+	Redirect RateLimitPolicy `yaml:"redirect"`
+}
+
+// RateLimitPolicy allows Requests per Per on average with bursts of up to Burst requests,
+// zero Requests disables the limit
+type RateLimitPolicy struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+	Burst    int           `yaml:"burst"`
+}
+
+type Save struct {
+	// ReuseExisting returns the user's existing link when the same url,
+	// after normalization, is saved again without an alias and expiry
+	ReuseExisting bool `yaml:"reuse_existing" env-default:"false"`
+	// BatchMaxItems is how many links POST /url/batch accepts at once
+	BatchMaxItems int `yaml:"batch_max_items" env-default:"1000"`
+}
+
+// Metrics are served in the Prometheus format on /metrics of the main server
+type Metrics struct {
+	Enabled bool `yaml:"enabled" env-default:"true"`
+}
+
+// Health configures the /healthz and /readyz probes
+type Health struct {
+	// ReadyTimeout limits the storage ping made by /readyz
+	ReadyTimeout time.Duration `yaml:"ready_timeout" env-default:"1s"`
+}
+
+// Tracing configures OpenTelemetry spans of requests and storage calls
+type Tracing struct {
+	// Exporter is none, stdout (spans are printed as JSON) or otlp
+	Exporter string `yaml:"exporter" env-default:"none"`
+	// Endpoint is host:port of the OTLP/HTTP collector
+	Endpoint string `yaml:"endpoint" env-default:"localhost:4318"`
+	// Insecure sends spans to the collector over plain HTTP
+	Insecure bool `yaml:"insecure" env-default:"true"`
+	// SampleRatio is the share of new traces that are recorded, traces
+	// started by the caller follow the caller's decision
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
+// Auth configures api keys of /url
+type Auth struct {
+	// BootstrapKey is an admin key created at startup if it does not exist yet.
+	// It is the only way to get a key for the memory storage.
+	BootstrapKey string `yaml:"bootstrap_key" env:"AUTH_BOOTSTRAP_KEY"`
+}
+
+// Alias configures aliases generated for links saved without one
+type Alias struct {
+	Generator string `yaml:"generator" env-default:"random"`
+	// Length of random aliases and the min length of sequential and hashids ones
+	Length int `yaml:"length" env-default:"6"`
+	// MaxLength is how long aliases may grow when collisions become frequent
+	MaxLength int `yaml:"max_length" env-default:"10"`
+	// Salt keys hashids aliases, changing it changes the aliases of new links
+	Salt string `yaml:"salt" env:"ALIAS_SALT"`
+	// Words is the number of words in words aliases
+	Words int `yaml:"words" env-default:"2"`
+	// MaxWords is how many words aliases may grow to
+	MaxWords int `yaml:"max_words" env-default:"4"`
+	// Custom are the rules for aliases chosen by users
+	Custom AliasRules `yaml:"custom"`
+}
+
+type AliasRules struct {
+	MinLength int `yaml:"min_length" env-default:"3"`
+	MaxLength int `yaml:"max_length" env-default:"64"`
+	// Charset lists every char an alias may contain
+	Charset string `yaml:"charset" env-default:"abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"`
+	// CaseFolding is none or lower, lower saves custom aliases in lower case
+	// and looks up every alias in lower case
+	CaseFolding string `yaml:"case_folding" env-default:"none"`
+	// Reserved aliases are added to the first segments of registered routes
+	Reserved []string `yaml:"reserved" env-default:"admin,api,health,static"`
+	// BlocklistFile has words that may not appear in aliases, one per line
+	BlocklistFile string `yaml:"blocklist_file"`
 }
 
 func MustLoad() *Config {
@@ -39,5 +214,85 @@ func MustLoad() *Config {
 		log.Fatalf("cannot read config: %v", err)
 	}
 
+	switch cfg.StorageType {
+	case StorageSQLite:
+		if cfg.StoragePath == "" {
+			log.Fatalf("storage_path is required for %s storage", cfg.StorageType)
+		}
+	case StoragePostgres:
+		if cfg.Postgres.DSN == "" {
+			log.Fatalf("postgres.dsn is required for %s storage", cfg.StorageType)
+		}
+	case StorageMemory:
+	default:
+		log.Fatalf("unknown storage_type %q", cfg.StorageType)
+	}
+
+	if cfg.Clicks.BufferSize < 0 || cfg.Clicks.BatchSize <= 0 || cfg.Clicks.FlushInterval <= 0 {
+		log.Fatalf("invalid clicks config: batch_size and flush_interval must be positive")
+	}
+
+	if cfg.Health.ReadyTimeout <= 0 {
+		log.Fatalf("invalid health.ready_timeout: must be positive")
+	}
+
+	if k := cfg.Auth.BootstrapKey; k != "" && len(k) < MinBootstrapKeyLength {
+		log.Fatalf("invalid auth.bootstrap_key: must be at least %d chars long", MinBootstrapKeyLength)
+	}
+
+	switch cfg.Tracing.Exporter {
+	case TracingNone, TracingStdout:
+	case TracingOTLP:
+		if cfg.Tracing.Endpoint == "" {
+			log.Fatalf("tracing.endpoint is required for the otlp exporter")
+		}
+	default:
+		log.Fatalf("unknown tracing.exporter %q", cfg.Tracing.Exporter)
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		log.Fatalf("invalid tracing.sample_ratio: must be between 0 and 1")
+	}
+
+	for name, p := range map[string]RateLimitPolicy{"save": cfg.RateLimit.Save, "redirect": cfg.RateLimit.Redirect} {
+		if p.Requests < 0 || p.Burst < 0 || (p.Requests > 0 && p.Per <= 0) {
+			log.Fatalf("invalid rate_limit.%s: requests and burst must not be negative, per must be positive", name)
+		}
+	}
+
+	switch cfg.Alias.Generator {
+	case AliasRandom, AliasSequential, AliasWords:
+	case AliasHashids:
+		if cfg.Alias.Salt == "" {
+			log.Fatalf("alias.salt is required for %s aliases", cfg.Alias.Generator)
+		}
+	default:
+		log.Fatalf("unknown alias.generator %q", cfg.Alias.Generator)
+	}
+	if cfg.Alias.Length < 1 || cfg.Alias.MaxLength < cfg.Alias.Length || cfg.Alias.MaxLength > 10 {
+		log.Fatalf("invalid alias.length %d and max_length %d: must be 1 <= length <= max_length <= 10", cfg.Alias.Length, cfg.Alias.MaxLength)
+	}
+	if cfg.Alias.Words < 1 || cfg.Alias.MaxWords < cfg.Alias.Words {
+		log.Fatalf("invalid alias.words %d and max_words %d: must be 1 <= words <= max_words", cfg.Alias.Words, cfg.Alias.MaxWords)
+	}
+
+	if cfg.Save.BatchMaxItems < 1 {
+		log.Fatalf("invalid save.batch_max_items %d: must be positive", cfg.Save.BatchMaxItems)
+	}
+
+	if r := cfg.Alias.Custom; r.MinLength < 1 || r.MaxLength < r.MinLength {
+		log.Fatalf("invalid alias.custom lengths %d and %d: must be 1 <= min_length <= max_length", r.MinLength, r.MaxLength)
+	}
+	switch cfg.Alias.Custom.CaseFolding {
+	case "none", "lower":
+	default:
+		log.Fatalf("invalid alias.custom.case_folding %q: must be none or lower", cfg.Alias.Custom.CaseFolding)
+	}
+
+	switch cfg.Redirect.StatusCode {
+	case 301, 302, 307, 308:
+	default:
+		log.Fatalf("invalid redirect status code %d: must be one of 301, 302, 307, 308", cfg.Redirect.StatusCode)
+	}
+
 	return &cfg
 }
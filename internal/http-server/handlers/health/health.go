@@ -0,0 +1,64 @@
+// Package health serves the probes of the orchestrator: /healthz tells
+// that the process is alive, /readyz that it can serve traffic.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/buildinfo"
+	"url-shortener/internal/lib/logger/sl"
+
+	"github.com/go-chi/render"
+)
+
+// Checker is the storage as seen by the readiness probe
+type Checker interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
+}
+
+type Response struct {
+	resp.Response
+	SchemaVersion int            `json:"schema_version"`
+	Build         buildinfo.Info `json:"build"`
+}
+
+// Live answers while the process can handle requests at all,
+// it does not look at the dependencies.
+func Live() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+// Ready pings the storage, waiting at most timeout, and answers 503
+// when it does not respond.
+func Ready(log *slog.Logger, checker Checker, timeout time.Duration, build buildinfo.Info) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.health.Ready"
+
+		log := log.With(slog.String("op", op))
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		res := Response{Response: resp.OK(), Build: build}
+
+		err := checker.Ping(ctx)
+		if err == nil {
+			res.SchemaVersion, err = checker.SchemaVersion(ctx)
+		}
+		if err != nil {
+			log.Warn("storage is not ready", sl.Err(err))
+
+			res.Response = resp.Error(resp.CodeUnavailable, "storage is unavailable")
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+
+		render.JSON(w, r, res)
+	}
+}
@@ -0,0 +1,93 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/buildinfo"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type checkerStub struct {
+	pingErr error
+	version int
+	// block makes Ping wait for the context
+	block bool
+}
+
+func (c checkerStub) Ping(ctx context.Context) error {
+	if c.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return c.pingErr
+}
+
+func (c checkerStub) SchemaVersion(ctx context.Context) (int, error) {
+	return c.version, nil
+}
+
+func TestLive(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Live().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"OK"}`, rr.Body.String())
+}
+
+func TestReady(t *testing.T) {
+	build := buildinfo.Info{Version: "v1.2.3", Commit: "abc", GoVersion: "go1.24"}
+
+	cases := []struct {
+		name       string
+		checker    checkerStub
+		wantStatus int
+		wantCode   string
+		wantSchema int
+	}{
+		{
+			name:       "ready",
+			checker:    checkerStub{version: 9},
+			wantStatus: http.StatusOK,
+			wantSchema: 9,
+		},
+		{
+			name:       "ping fails",
+			checker:    checkerStub{pingErr: errors.New("connection refused")},
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   resp.CodeUnavailable,
+		},
+		{
+			name:       "ping times out",
+			checker:    checkerStub{block: true},
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   resp.CodeUnavailable,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := Ready(slogdiscard.NewDiscardLogger(), tc.checker, 10*time.Millisecond, build)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var res Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantCode, res.Code)
+			assert.Equal(t, tc.wantSchema, res.SchemaVersion)
+			assert.Equal(t, build, res.Build)
+		})
+	}
+}
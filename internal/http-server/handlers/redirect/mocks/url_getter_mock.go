@@ -3,6 +3,7 @@
 package mocks
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -13,44 +14,44 @@ import (
 type URLGetterMock struct {
 	t *testing.T
 	// GetURLFunc allows setting custom behavior for GetURL method
-	GetURLFunc func(alias string) (string, error)
+	GetURLFunc func(alias string) (storage.URL, error)
 	// Mock expectations
-	expectations map[string]func() (string, error)
+	expectations map[string]func() (storage.URL, error)
 }
 
 // NewURLGetterMock creates a new mock instance
 func NewURLGetterMock(t *testing.T) *URLGetterMock {
 	return &URLGetterMock{
 		t: t,
-		expectations: make(map[string]func() (string, error)),
-		GetURLFunc: func(alias string) (string, error) {
+		expectations: make(map[string]func() (storage.URL, error)),
+		GetURLFunc: func(alias string) (storage.URL, error) {
 			t.Errorf("GetURL was called but not mocked")
-			return "", errors.New("not mocked")
+			return storage.URL{}, errors.New("not mocked")
 		},
 	}
 }
 
 // GetURL calls the mocked function
-func (m *URLGetterMock) GetURL(alias string) (string, error) {
+func (m *URLGetterMock) GetURL(_ context.Context, alias string) (storage.URL, error) {
 	return m.GetURLFunc(alias)
 }
 
 // Helper methods for common scenarios
-func (m *URLGetterMock) SetGetURLSuccess(url string) {
-	m.GetURLFunc = func(alias string) (string, error) {
-		return url, nil
+func (m *URLGetterMock) SetGetURLSuccess(u storage.URL) {
+	m.GetURLFunc = func(alias string) (storage.URL, error) {
+		return u, nil
 	}
 }
 
 func (m *URLGetterMock) SetGetURLError(err error) {
-	m.GetURLFunc = func(alias string) (string, error) {
-		return "", err
+	m.GetURLFunc = func(alias string) (storage.URL, error) {
+		return storage.URL{}, err
 	}
 }
 
 func (m *URLGetterMock) SetGetURLNotFoundError() {
-	m.GetURLFunc = func(alias string) (string, error) {
-		return "", storage.ErrURLNotFound
+	m.GetURLFunc = func(alias string) (storage.URL, error) {
+		return storage.URL{}, storage.ErrURLNotFound
 	}
 }
 
@@ -82,18 +83,18 @@ func (e *MockExpectation) Once() {
 	if e.method == "GetURL" && len(e.args) == 1 {
 		alias := e.args[0].(string)
 		if len(e.returns) == 2 {
-			url := e.returns[0].(string)
+			u := e.returns[0].(storage.URL)
 			err := e.returns[1]
 			var errVal error
 			if err != nil {
 				errVal = err.(error)
 			}
-			e.mock.GetURLFunc = func(calledAlias string) (string, error) {
+			e.mock.GetURLFunc = func(calledAlias string) (storage.URL, error) {
 				if calledAlias == alias {
-					return url, errVal
+					return u, errVal
 				}
 				e.mock.t.Errorf("unexpected alias: expected %s, got %s", alias, calledAlias)
-				return "", errors.New("unexpected alias")
+				return storage.URL{}, errors.New("unexpected alias")
 			}
 		}
 	}
@@ -1,9 +1,11 @@
 package redirect
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/sl"
@@ -18,14 +20,45 @@ import (
 //
 // go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLGetter
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	GetURL(ctx context.Context, alias string) (storage.URL, error)
 }
 
-func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+// ClickRecorder receives an event for every resolved alias.
+// It must not block: recording happens on the redirect hot path.
+type ClickRecorder interface {
+	Record(c storage.Click)
+}
+
+// IsRedirectCode reports whether code is one of the redirect statuses
+// a short link may use: 301, 302, 307 or 308.
+func IsRedirectCode(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// RedirectCounter counts lookups by result: "hit", "miss" or "expired".
+type RedirectCounter interface {
+	CountRedirect(result string)
+}
+
+// New returns handler that redirects to the url saved under the alias.
+// defaultCode is used for links that have no redirect status of their own.
+// clickRecorder and counter may be nil when analytics are not needed.
+func New(log *slog.Logger, urlGetter URLGetter, clickRecorder ClickRecorder, counter RedirectCounter, defaultCode int) http.HandlerFunc {
+	count := func(result string) {
+		if counter != nil {
+			counter.CountRedirect(result)
+		}
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.redirect.New"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
@@ -34,30 +67,63 @@ func New(log *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
 		if alias == "" {
 			log.Info("alias is empty")
 
-			render.JSON(w, r, resp.Error("invalid request"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeBadRequest, "invalid request"))
 
 			return
 		}
 
-		resURL, err := urlGetter.GetURL(alias)
+		res, err := urlGetter.GetURL(r.Context(), alias)
 		if errors.Is(err, storage.ErrURLNotFound) {
 			log.Info("url not found", "alias", alias)
+			count("miss")
 
-			render.JSON(w, r, resp.Error("not found"))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
 
 			return
 		}
 		if err != nil {
 			log.Error("failed to get url", sl.Err(err))
 
-			render.JSON(w, r, resp.Error("internal error"))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
 
 			return
 		}
 
-		log.Info("got url", slog.String("url", resURL))
+		now := time.Now()
+		if res.Expired(now) {
+			log.Info("url expired", slog.String("alias", alias), slog.Time("expires_at", res.ExpiresAt))
+			count("expired")
+
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, resp.Error(resp.CodeGone, "link expired"))
+
+			return
+		}
+
+		code := res.RedirectCode
+		if !IsRedirectCode(code) {
+			code = defaultCode
+		}
+
+		log.Info("got url", slog.String("url", res.URL), slog.Int("code", code))
+		count("hit")
+
+		if clickRecorder != nil {
+			clickRecorder.Record(storage.Click{
+				URLID:      res.ID,
+				Alias:      res.Alias,
+				ClickedAt:  now,
+				Referrer:   r.Referer(),
+				UserAgent:  r.UserAgent(),
+				RemoteAddr: r.RemoteAddr,
+				RequestID:  middleware.GetReqID(r.Context()),
+			})
+		}
 
 		// redirect to found url
-		http.Redirect(w, r, resURL, http.StatusFound)
+		http.Redirect(w, r, res.URL, code)
 	}
 }
@@ -1,63 +1,205 @@
 package redirect
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"url-shortener/internal/http-server/handlers/redirect/mocks"
+	"url-shortener/internal/http-server/middleware/foldalias"
+	"url-shortener/internal/lib/alias"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
 
-	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestSaveHandler(t *testing.T) {
 	cases := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
+		name         string
+		alias        string
+		url          string
+		redirectCode int
+		defaultCode  int
+		wantCode     int
 	}{
-		{name: "Success", alias: "test_alias", url: "https://www.google.com"},
+		{name: "Success", alias: "test_alias", url: "https://www.google.com", defaultCode: http.StatusFound, wantCode: http.StatusFound},
+		{name: "Default code", alias: "seo", url: "https://www.google.com", defaultCode: http.StatusMovedPermanently, wantCode: http.StatusMovedPermanently},
+		{name: "Per-link code", alias: "callback", url: "https://www.google.com", redirectCode: http.StatusTemporaryRedirect, defaultCode: http.StatusFound, wantCode: http.StatusTemporaryRedirect},
+		{name: "Per-link permanent code", alias: "moved", url: "https://www.google.com", redirectCode: http.StatusPermanentRedirect, defaultCode: http.StatusFound, wantCode: http.StatusPermanentRedirect},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			urlGettingMock := mocks.NewURLGetterMock(t)
 
-			// Setup mock directly
-			urlGettingMock.GetURLFunc = func(alias string) (string, error) {
-				t.Logf("Mock called with alias: %s", alias)
-				return tc.url, nil
-			}
+			urlGettingMock.On("GetURL", tc.alias).
+				Return(storage.URL{Alias: tc.alias, URL: tc.url, RedirectCode: tc.redirectCode}, nil).
+				Once()
 
 			r := chi.NewRouter()
-			r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), urlGettingMock))
+			r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), urlGettingMock, nil, nil, tc.defaultCode))
 
 			ts := httptest.NewServer(r)
 			defer ts.Close()
 
-			// Test redirect - first check what status we get
-			testURL := ts.URL + "/" + tc.alias
-			t.Logf("Testing URL: %s", testURL)
+			client := &http.Client{
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
 
-			resp, err := http.Get(testURL)
+			resp, err := client.Get(ts.URL + "/" + tc.alias)
 			require.NoError(t, err)
 			defer resp.Body.Close()
 
-			t.Logf("Response status: %d", resp.StatusCode)
-			t.Logf("Response headers: %v", resp.Header)
-
-			// Just check that we get some response
-			if resp.StatusCode == 200 {
-				t.Logf("Got 200 response, this might be an error response")
-			} else if resp.StatusCode == 302 {
-				location := resp.Header.Get("Location")
-				t.Logf("Got redirect to: %s", location)
-				assert.Equal(t, tc.url, location)
-			}
+			assert.Equal(t, tc.wantCode, resp.StatusCode)
+			assert.Equal(t, tc.url, resp.Header.Get("Location"))
 		})
 	}
 }
+
+func TestRedirectWithMemoryStorage(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := memory.New()
+	_, err := urlStorage.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), urlStorage, nil, nil, http.StatusFound))
+
+	req := httptest.NewRequest(http.MethodGet, "/google", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://www.google.com", rr.Header().Get("Location"))
+
+	req = httptest.NewRequest(http.MethodGet, "/missing", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Contains(t, rr.Body.String(), "not found")
+}
+
+func TestRedirectFoldsAliasCase(t *testing.T) {
+	ctx := context.Background()
+
+	policy := alias.NewPolicy(alias.Rules{FoldCase: true})
+
+	// ссылка сохранена под уже приведённым алиасом, как это делает save
+	urlStorage := memory.New()
+	_, err := urlStorage.SaveURL(ctx, storage.URL{Alias: policy.Normalize("MyLink"), URL: "https://www.google.com"})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.With(foldalias.New(policy)).Get("/{alias}", New(slogdiscard.NewDiscardLogger(), urlStorage, nil, nil, http.StatusFound))
+
+	for _, path := range []string{"/MyLink", "/mylink", "/MYLINK"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusFound, rr.Code, path)
+		assert.Equal(t, "https://www.google.com", rr.Header().Get("Location"), path)
+	}
+}
+
+func TestRedirectExpired(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := memory.New()
+	_, err := urlStorage.SaveURL(ctx, storage.URL{
+		Alias:     "campaign",
+		URL:       "https://www.google.com",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), urlStorage, nil, nil, http.StatusFound))
+
+	req := httptest.NewRequest(http.MethodGet, "/campaign", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusGone, rr.Code)
+	assert.Empty(t, rr.Header().Get("Location"))
+}
+
+type clickRecorderStub struct {
+	clicks []storage.Click
+}
+
+func (s *clickRecorderStub) Record(c storage.Click) {
+	s.clicks = append(s.clicks, c)
+}
+
+func TestRedirectRecordsClick(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := memory.New()
+	id, err := urlStorage.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	require.NoError(t, err)
+
+	recorder := &clickRecorderStub{}
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), urlStorage, recorder, nil, http.StatusFound))
+
+	req := httptest.NewRequest(http.MethodGet, "/google", nil)
+	req.Header.Set("Referer", "https://news.example.com")
+	req.Header.Set("User-Agent", "test-agent")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	require.Len(t, recorder.clicks, 1)
+	c := recorder.clicks[0]
+	assert.Equal(t, id, c.URLID)
+	assert.Equal(t, "google", c.Alias)
+	assert.Equal(t, "https://news.example.com", c.Referrer)
+	assert.Equal(t, "test-agent", c.UserAgent)
+	assert.Equal(t, req.RemoteAddr, c.RemoteAddr)
+	assert.False(t, c.ClickedAt.IsZero())
+
+	// missing links are not counted
+	req = httptest.NewRequest(http.MethodGet, "/missing", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Len(t, recorder.clicks, 1)
+}
+
+type redirectCounterStub map[string]int
+
+func (s redirectCounterStub) CountRedirect(result string) {
+	s[result]++
+}
+
+func TestRedirectCountsResults(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := memory.New()
+	_, err := urlStorage.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	require.NoError(t, err)
+	_, err = urlStorage.SaveURL(ctx, storage.URL{
+		Alias:     "campaign",
+		URL:       "https://www.google.com",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	counter := redirectCounterStub{}
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", New(slogdiscard.NewDiscardLogger(), urlStorage, nil, counter, http.StatusFound))
+
+	for _, path := range []string{"/google", "/google", "/missing", "/campaign"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, redirectCounterStub{"hit": 2, "miss": 1, "expired": 1}, counter)
+}
@@ -0,0 +1,291 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/http-server/middleware/ratelimit"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// modes of saving a batch
+const (
+	// ModeAllOrNothing saves every item or none of them
+	ModeAllOrNothing = "all_or_nothing"
+	// ModeBestEffort saves every item it can and reports the rest
+	ModeBestEffort = "best_effort"
+)
+
+// maxRetries is how many times generated aliases that turned out taken are regenerated
+const maxRetries = 4
+
+type Request struct {
+	Items []save.Request `json:"items"`
+	// Mode is all_or_nothing when empty
+	Mode string `json:"mode,omitempty"`
+}
+
+// Item is the result of saving one item, Index is its position in the request
+type Item struct {
+	resp.Response
+	Index     int        `json:"index"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type Response struct {
+	resp.Response
+	Saved  int    `json:"saved"`
+	Failed int    `json:"failed"`
+	Items  []Item `json:"items,omitempty"`
+}
+
+// go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLSaver
+type URLSaver interface {
+	SaveURLs(ctx context.Context, urls []storage.URL, allOrNothing bool) ([]int64, error)
+}
+
+// pending is a valid item waiting to be saved
+type pending struct {
+	index     int
+	link      storage.URL
+	generated bool
+}
+
+// конструктор для handler, сохраняющего до maxItems ссылок за один запрос.
+// Элементы проверяются так же, как в save; reuse_existing к ним не применяется.
+func New(log *slog.Logger, urlSaver URLSaver, aliases save.AliasGenerator, policy save.AliasPolicy, maxItems int) http.HandlerFunc {
+	validate := save.NewValidator(policy)
+
+	observe := func(bool) {}
+	if o, ok := aliases.(save.CollisionObserver); ok {
+		observe = o.Observe
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.batch.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeBadRequest, "failed to decode request"))
+			return
+		}
+
+		if fieldErr := checkRequest(req, maxItems); fieldErr != nil {
+			log.Info("invalid batch", slog.String("error", fieldErr.Message))
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, resp.FieldsError(*fieldErr))
+			return
+		}
+		allOrNothing := req.Mode != ModeBestEffort
+
+		// лимит сохранения считается в ссылках: один токен уже взят при входе
+		ratelimit.Charge(r.Context(), len(req.Items)-1)
+
+		var ownerID int64
+		if user, ok := auth.UserFromContext(r.Context()); ok {
+			ownerID = user.ID
+		}
+
+		// проверяем все элементы до сохранения
+		results := make([]Item, len(req.Items))
+		var todo []pending
+		now := time.Now()
+		for i, item := range req.Items {
+			results[i].Index = i
+			item.Alias = policy.Normalize(item.Alias)
+
+			if err := validate.Struct(item); err != nil {
+				results[i].Response = resp.ValidationError(err.(validator.ValidationErrors))
+				continue
+			}
+			expiresAt, fieldErr := item.Expiry(now)
+			if fieldErr != nil {
+				results[i].Response = resp.FieldsError(*fieldErr)
+				continue
+			}
+
+			todo = append(todo, pending{
+				index: i,
+				link: storage.URL{
+					Alias:        item.Alias,
+					URL:          item.URL,
+					RedirectCode: item.RedirectCode,
+					ExpiresAt:    expiresAt,
+					OwnerID:      ownerID,
+				},
+				generated: item.Alias == "",
+			})
+		}
+
+		if allOrNothing && len(todo) < len(req.Items) {
+			log.Info("batch has invalid items", slog.Int("invalid", len(req.Items)-len(todo)))
+			abort(todo, results)
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response(resp.Error(resp.CodeValidationFailed, "some items are invalid"), results))
+			return
+		}
+
+		for i := range todo {
+			if !todo[i].generated {
+				continue
+			}
+			alias, err := save.GenerateAlias(aliases, policy)
+			if err != nil {
+				log.Error("failed to generate alias", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to generate alias"))
+				return
+			}
+			todo[i].link.Alias = alias
+		}
+
+		for attempt := 0; len(todo) > 0; attempt++ {
+			links := make([]storage.URL, len(todo))
+			for i, p := range todo {
+				links[i] = p.link
+			}
+
+			ids, err := urlSaver.SaveURLs(r.Context(), links, allOrNothing)
+			if errors.Is(err, storage.ErrUserNotFound) {
+				// владелец ключа удалён после проверки ключа, как и в auth
+				log.Info("owner of the links does not exist", slog.Int64("owner_id", ownerID))
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error(resp.CodeUnauthorized, "invalid api key"))
+				return
+			}
+			if err != nil && !errors.Is(err, storage.ErrURLExists) {
+				log.Error("failed to save urls", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to save urls"))
+				return
+			}
+			committed := err == nil
+
+			var retry []pending
+			conflict := false
+			for i, p := range todo {
+				res := &results[p.index]
+				switch {
+				case ids[i] != 0:
+					if !committed {
+						// сохранится при следующей попытке вместе с остальными
+						retry = append(retry, p)
+						continue
+					}
+					if p.generated {
+						observe(false)
+					}
+					res.Response = resp.OK()
+					res.Alias = p.link.Alias
+					if !p.link.ExpiresAt.IsZero() {
+						expiresAt := p.link.ExpiresAt
+						res.ExpiresAt = &expiresAt
+					}
+				case !p.generated:
+					conflict = true
+					res.Response = resp.Error(resp.CodeAliasExists, "alias already exists")
+				case attempt == maxRetries:
+					observe(true)
+					res.Response = resp.Error(resp.CodeInternal, "could not generate unique alias")
+				default:
+					observe(true)
+					if p.link.Alias, err = save.GenerateAlias(aliases, policy); err != nil {
+						log.Error("failed to generate alias", sl.Err(err))
+						res.Response = resp.Error(resp.CodeInternal, "failed to generate alias")
+						continue
+					}
+					retry = append(retry, p)
+				}
+			}
+
+			if !committed && (conflict || len(retry) < len(todo)) {
+				// хотя бы один элемент не может быть сохранён — не сохраняем ничего
+				log.Info("batch not saved", slog.Bool("alias_conflict", conflict))
+				abort(todo, results)
+				status, msg, code := http.StatusConflict, "some aliases already exist", resp.CodeAliasExists
+				if !conflict {
+					status, msg, code = http.StatusInternalServerError, "could not save some items", resp.CodeInternal
+				}
+				render.Status(r, status)
+				render.JSON(w, r, response(resp.Error(code, msg), results))
+				return
+			}
+
+			todo = retry
+		}
+
+		res := response(resp.OK(), results)
+		log.Info("batch saved", slog.Int("saved", res.Saved), slog.Int("failed", res.Failed))
+
+		render.JSON(w, r, res)
+	}
+}
+
+// checkRequest returns what is wrong with the batch as a whole
+func checkRequest(req Request, maxItems int) *resp.FieldError {
+	switch {
+	case len(req.Items) == 0:
+		return &resp.FieldError{Field: "items", Rule: "required", Message: "field items is a required field"}
+	case len(req.Items) > maxItems:
+		return &resp.FieldError{
+			Field:   "items",
+			Rule:    "max",
+			Param:   fmt.Sprint(maxItems),
+			Message: fmt.Sprintf("field items must have at most %d items", maxItems),
+		}
+	}
+
+	switch req.Mode {
+	case "", ModeAllOrNothing, ModeBestEffort:
+	default:
+		return &resp.FieldError{
+			Field:   "mode",
+			Rule:    "oneof",
+			Param:   ModeAllOrNothing + " " + ModeBestEffort,
+			Message: "field mode must be one of " + ModeAllOrNothing + ", " + ModeBestEffort,
+		}
+	}
+
+	return nil
+}
+
+// abort marks items that have no result yet as not saved
+func abort(todo []pending, results []Item) {
+	for _, p := range todo {
+		if results[p.index].Status == "" {
+			results[p.index].Response = resp.Error(resp.CodeNotSaved, "not saved because other items failed")
+		}
+	}
+}
+
+func response(r resp.Response, items []Item) Response {
+	res := Response{Response: r, Items: items}
+	for _, item := range items {
+		if item.Status == resp.StatusOk {
+			res.Saved++
+		} else {
+			res.Failed++
+		}
+	}
+	return res
+}
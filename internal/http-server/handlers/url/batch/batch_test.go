@@ -0,0 +1,204 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/alias"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchHandler(t *testing.T) {
+	alice := storage.User{ID: 1, Name: "alice", Role: storage.RoleEditor}
+
+	cases := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+		// wantItems are codes of item results, "" for saved items
+		wantItems []string
+		// wantAliases are saved in the storage afterwards
+		wantAliases []string
+	}{
+		{
+			name:        "All saved",
+			body:        `{"items": [{"url": "https://example.com/1", "alias": "one"}, {"url": "https://example.com/2"}]}`,
+			wantStatus:  http.StatusOK,
+			wantItems:   []string{"", ""},
+			wantAliases: []string{"one", "000001"},
+		},
+		{
+			name:       "Invalid item aborts the batch",
+			body:       `{"items": [{"url": "https://example.com/1", "alias": "one"}, {"url": "not a url"}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   resp.CodeValidationFailed,
+			wantItems:  []string{resp.CodeNotSaved, resp.CodeValidationFailed},
+		},
+		{
+			name:       "Taken alias aborts the batch",
+			body:       `{"items": [{"url": "https://example.com/1", "alias": "one"}, {"url": "https://example.com/2", "alias": "taken"}]}`,
+			wantStatus: http.StatusConflict,
+			wantCode:   resp.CodeAliasExists,
+			wantItems:  []string{resp.CodeNotSaved, resp.CodeAliasExists},
+		},
+		{
+			name:       "Duplicate aliases in one batch",
+			body:       `{"items": [{"url": "https://example.com/1", "alias": "one"}, {"url": "https://example.com/2", "alias": "one"}]}`,
+			wantStatus: http.StatusConflict,
+			wantCode:   resp.CodeAliasExists,
+			wantItems:  []string{resp.CodeNotSaved, resp.CodeAliasExists},
+		},
+		{
+			name: "Best effort saves what it can",
+			body: `{"mode": "best_effort", "items": [
+				{"url": "https://example.com/1", "alias": "one"},
+				{"url": "https://example.com/2", "alias": "taken"},
+				{"url": "https://example.com/3", "ttl": "soon"},
+				{"url": "https://example.com/4", "alias": "url"}
+			]}`,
+			wantStatus:  http.StatusOK,
+			wantItems:   []string{"", resp.CodeAliasExists, resp.CodeValidationFailed, resp.CodeValidationFailed},
+			wantAliases: []string{"one"},
+		},
+		{
+			name:       "Too many items",
+			body:       `{"items": [{"url": "https://a.com"}, {"url": "https://b.com"}, {"url": "https://c.com"}, {"url": "https://d.com"}, {"url": "https://e.com"}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   resp.CodeValidationFailed,
+		},
+		{
+			name:       "No items",
+			body:       `{"items": []}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   resp.CodeValidationFailed,
+		},
+		{
+			name:       "Unknown mode",
+			body:       `{"mode": "some", "items": [{"url": "https://a.com"}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   resp.CodeValidationFailed,
+		},
+		{
+			name:       "Bad body",
+			body:       `{`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   resp.CodeBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			urlStorage := memory.New()
+			_, err := urlStorage.SaveURL(ctx, storage.URL{Alias: "taken", URL: "https://example.com"})
+			require.NoError(t, err)
+
+			handler := New(slogdiscard.NewDiscardLogger(), urlStorage, alias.NewSequential(alias.NewCounter(0), 6), testPolicy(), 4)
+
+			req := httptest.NewRequest(http.MethodPost, "/url/batch", strings.NewReader(tc.body))
+			req = req.WithContext(auth.WithUser(req.Context(), alice))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+
+			var res Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantCode, res.Code)
+
+			codes := make([]string, 0, len(res.Items))
+			for i, item := range res.Items {
+				assert.Equal(t, i, item.Index)
+				codes = append(codes, item.Code)
+			}
+			if tc.wantItems != nil {
+				assert.Equal(t, tc.wantItems, codes)
+			}
+
+			list, total, err := urlStorage.ListURLs(ctx, storage.ListParams{OwnerID: alice.ID})
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tc.wantAliases)), total)
+			for i, u := range list {
+				assert.Equal(t, tc.wantAliases[i], u.Alias)
+			}
+		})
+	}
+}
+
+func TestBatchRegeneratesTakenAliases(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := memory.New()
+	_, err := urlStorage.SaveURL(ctx, storage.URL{Alias: "000001", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	handler := New(slogdiscard.NewDiscardLogger(), urlStorage, alias.NewSequential(alias.NewCounter(0), 6), testPolicy(), 10)
+
+	for _, mode := range []string{ModeAllOrNothing, ModeBestEffort} {
+		t.Run(mode, func(t *testing.T) {
+			body := `{"mode": "` + mode + `", "items": [{"url": "https://example.com/` + mode + `"}]}`
+			req := httptest.NewRequest(http.MethodPost, "/url/batch", strings.NewReader(body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var res Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+			require.Len(t, res.Items, 1)
+			assert.Equal(t, resp.StatusOk, res.Items[0].Status)
+			assert.NotEqual(t, "000001", res.Items[0].Alias)
+
+			u, err := urlStorage.GetURL(ctx, res.Items[0].Alias)
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/"+mode, u.URL)
+		})
+	}
+}
+
+// ownerlessSaver fails every save as if the key owner was deleted
+type ownerlessSaver struct{}
+
+func (ownerlessSaver) SaveURLs(context.Context, []storage.URL, bool) ([]int64, error) {
+	return nil, storage.ErrUserNotFound
+}
+
+func TestBatchUnknownOwner(t *testing.T) {
+	alice := storage.User{ID: 1, Name: "alice", Role: storage.RoleEditor}
+
+	handler := New(slogdiscard.NewDiscardLogger(), ownerlessSaver{}, alias.NewSequential(alias.NewCounter(0), 6), testPolicy(), 10)
+
+	body := `{"items": [{"url": "https://example.com"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/url/batch", strings.NewReader(body))
+	req = req.WithContext(auth.WithUser(req.Context(), alice))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	var res resp.Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.Equal(t, resp.CodeUnauthorized, res.Code)
+}
+
+func testPolicy() *alias.Policy {
+	return alias.NewPolicy(alias.Rules{
+		MinLength: 3,
+		MaxLength: 32,
+		Charset:   "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_",
+		Reserved:  []string{"url"},
+	})
+}
@@ -1,9 +1,12 @@
 package delete
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 
+	"url-shortener/internal/http-server/middleware/auth"
 	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/storage"
@@ -15,7 +18,8 @@ import (
 
 // go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLDeleter
 type URLDeleter interface {
-	DeleteURL(alias string) error
+	GetURL(ctx context.Context, alias string) (storage.URL, error)
+	DeleteURL(ctx context.Context, alias string) error
 }
 
 // конструктор для handler удаления URL
@@ -23,7 +27,7 @@ func New(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.delete.New"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
@@ -32,23 +36,48 @@ func New(log *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
 		alias := chi.URLParam(r, "alias")
 		if alias == "" {
 			log.Error("alias is empty")
-			render.JSON(w, r, resp.Error("invalid request"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeBadRequest, "invalid request"))
 			return
 		}
 
 		log.Info("attempting to delete url", slog.String("alias", alias))
 
+		// удалить ссылку может только её владелец или админ
+		u, err := urlDeleter.GetURL(r.Context(), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "url not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to delete url"))
+			return
+		}
+
+		if !auth.CanManage(r.Context(), u.OwnerID) {
+			log.Info("link belongs to another user", slog.String("alias", alias))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(resp.CodeForbidden, "not allowed to manage this link"))
+			return
+		}
+
 		// удаляем URL по alias
-		err := urlDeleter.DeleteURL(alias)
+		err = urlDeleter.DeleteURL(r.Context(), alias)
 		if err != nil {
 			if err == storage.ErrURLNotFound {
 				log.Info("url not found", slog.String("alias", alias))
-				render.JSON(w, r, resp.Error("url not found"))
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error(resp.CodeNotFound, "url not found"))
 				return
 			}
 
 			log.Error("failed to delete url", sl.Err(err))
-			render.JSON(w, r, resp.Error("failed to delete url"))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to delete url"))
 			return
 		}
 
@@ -0,0 +1,68 @@
+package delete
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteHandler(t *testing.T) {
+	ctx := context.Background()
+
+	alice := storage.User{ID: 1, Name: "alice"}
+	bob := storage.User{ID: 2, Name: "bob"}
+	admin := storage.User{ID: 3, Name: "admin", Role: storage.RoleAdmin}
+
+	urlStorage := memory.New()
+	for _, u := range []storage.URL{
+		{Alias: "alices", URL: "https://alice.example.com", OwnerID: alice.ID},
+		{Alias: "bobs", URL: "https://bob.example.com", OwnerID: bob.ID},
+	} {
+		_, err := urlStorage.SaveURL(ctx, u)
+		require.NoError(t, err)
+	}
+
+	r := chi.NewRouter()
+	r.Delete("/url/{alias}", New(slogdiscard.NewDiscardLogger(), urlStorage))
+
+	cases := []struct {
+		name       string
+		alias      string
+		user       storage.User
+		wantStatus int
+		wantError  string
+	}{
+		{name: "Other user", alias: "bobs", user: alice, wantStatus: http.StatusForbidden, wantError: "not allowed to manage this link"},
+		{name: "Owner", alias: "alices", user: alice, wantStatus: http.StatusOK},
+		{name: "Admin", alias: "bobs", user: admin, wantStatus: http.StatusOK},
+		{name: "Not found", alias: "bobs", user: admin, wantStatus: http.StatusNotFound, wantError: "url not found"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/url/"+tc.alias, nil)
+			req = req.WithContext(auth.WithUser(req.Context(), tc.user))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			var res struct {
+				Error string `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantError, res.Error)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package get
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHandler(t *testing.T) {
+	urlStorage := memory.New()
+	id, err := urlStorage.SaveURL(context.Background(), storage.URL{Alias: "google", URL: "https://www.google.com", RedirectCode: 301, OwnerID: 1})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Get("/url/{alias}", New(slogdiscard.NewDiscardLogger(), urlStorage))
+
+	get := func(path string, user storage.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(auth.WithUser(req.Context(), user))
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	owner := storage.User{ID: 1, Name: "alice"}
+
+	rr := get("/url/google", owner)
+
+	var res Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.Equal(t, "OK", res.Status)
+	assert.Equal(t, id, res.ID)
+	assert.Equal(t, "https://www.google.com", res.URL)
+	assert.Equal(t, 301, res.RedirectCode)
+	assert.NotNil(t, res.CreatedAt)
+	assert.Nil(t, res.ExpiresAt)
+
+	rr = get("/url/missing", owner)
+	res = Response{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.Equal(t, "not found", res.Error)
+
+	rr = get("/url/google", storage.User{ID: 2, Name: "bob"})
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = get("/url/google", storage.User{ID: 3, Name: "admin", Role: storage.RoleAdmin})
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+// запускать с -race: хендлер не должен менять общий логгер
+func TestGetHandlerConcurrent(t *testing.T) {
+	urlStorage := memory.New()
+	_, err := urlStorage.SaveURL(context.Background(), storage.URL{Alias: "google", URL: "https://www.google.com", OwnerID: 1})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Get("/url/{alias}", New(slogdiscard.NewDiscardLogger(), urlStorage))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req := httptest.NewRequest(http.MethodGet, "/url/google", nil)
+			req = req.WithContext(auth.WithUser(req.Context(), storage.User{ID: 1, Name: "alice"}))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,135 @@
+package list
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/api/link"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLLister
+type URLLister interface {
+	ListURLs(ctx context.Context, params storage.ListParams) ([]storage.URL, int64, error)
+}
+
+type Response struct {
+	resp.Response
+	Items  []link.Link `json:"items"`
+	Total  int64       `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// конструктор для handler списка ссылок.
+// ?limit=, ?offset= — страница; ?sort=id|alias|url|created_at и ?order=asc|desc — сортировка;
+// ?q= — подстрока алиаса или адреса
+func New(log *slog.Logger, urlLister URLLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.list.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		params, fieldErr := parseParams(r)
+		if fieldErr != nil {
+			log.Info("invalid list params", slog.String("error", fieldErr.Message))
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, resp.FieldsError(*fieldErr))
+			return
+		}
+
+		// админ видит все ссылки, остальные — только свои
+		user, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			log.Error("no user in request context")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+			return
+		}
+		if !user.IsAdmin() {
+			params.OwnerID = user.ID
+		}
+
+		urls, total, err := urlLister.ListURLs(r.Context(), params)
+		if err != nil {
+			log.Error("failed to list urls", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+			return
+		}
+
+		res := Response{
+			Response: resp.OK(),
+			Items:    make([]link.Link, 0, len(urls)),
+			Total:    total,
+			Limit:    params.Limit,
+			Offset:   params.Offset,
+		}
+		for _, u := range urls {
+			res.Items = append(res.Items, link.FromURL(u))
+		}
+
+		render.JSON(w, r, res)
+	}
+}
+
+// parseParams reads query parameters, the second value is a message for the client
+func parseParams(r *http.Request) (storage.ListParams, *resp.FieldError) {
+	q := r.URL.Query()
+
+	params := storage.ListParams{
+		Limit:  defaultLimit,
+		SortBy: storage.SortByID,
+		Query:  q.Get("q"),
+	}
+
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxLimit {
+			return params, &resp.FieldError{Field: "limit", Rule: "range", Param: "1-100", Message: "limit must be a number from 1 to 100"}
+		}
+		params.Limit = n
+	}
+
+	if raw := q.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return params, &resp.FieldError{Field: "offset", Rule: "min", Param: "0", Message: "offset must be a non-negative number"}
+		}
+		params.Offset = n
+	}
+
+	switch sortBy := q.Get("sort"); sortBy {
+	case "":
+	case storage.SortByID, storage.SortByAlias, storage.SortByURL, storage.SortByCreatedAt:
+		params.SortBy = sortBy
+	default:
+		return params, &resp.FieldError{Field: "sort", Rule: "oneof", Param: "id alias url created_at", Message: "sort must be one of id, alias, url, created_at"}
+	}
+
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		params.Desc = true
+	default:
+		return params, &resp.FieldError{Field: "order", Rule: "oneof", Param: "asc desc", Message: "order must be asc or desc"}
+	}
+
+	return params, nil
+}
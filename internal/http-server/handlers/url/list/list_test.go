@@ -0,0 +1,105 @@
+package list
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListHandler(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := memory.New()
+	alice := storage.User{ID: 1, Name: "alice"}
+	for _, u := range []storage.URL{
+		{Alias: "google", URL: "https://www.google.com", OwnerID: alice.ID},
+		{Alias: "go", URL: "https://go.dev", OwnerID: alice.ID},
+		{Alias: "mail", URL: "https://mail.ru", OwnerID: alice.ID},
+		{Alias: "bobs", URL: "https://bob.example.com", OwnerID: 2},
+	} {
+		_, err := urlStorage.SaveURL(ctx, u)
+		require.NoError(t, err)
+	}
+
+	handler := New(slogdiscard.NewDiscardLogger(), urlStorage)
+
+	cases := []struct {
+		name        string
+		query       string
+		wantAliases []string
+		wantTotal   int64
+		wantError   string
+		wantField   string
+	}{
+		{name: "Defaults", query: "", wantAliases: []string{"google", "go", "mail"}, wantTotal: 3},
+		{name: "Page", query: "?limit=1&offset=1", wantAliases: []string{"go"}, wantTotal: 3},
+		{name: "Sort desc", query: "?sort=alias&order=desc", wantAliases: []string{"mail", "google", "go"}, wantTotal: 3},
+		{name: "Filter", query: "?q=GO", wantAliases: []string{"google", "go"}, wantTotal: 2},
+		{name: "Limit too big", query: "?limit=1000", wantError: "limit must be a number from 1 to 100", wantField: "limit"},
+		{name: "Negative offset", query: "?offset=-1", wantError: "offset must be a non-negative number", wantField: "offset"},
+		{name: "Unknown sort", query: "?sort=password", wantError: "sort must be one of id, alias, url, created_at", wantField: "sort"},
+		{name: "Unknown order", query: "?order=up", wantError: "order must be asc or desc", wantField: "order"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/url"+tc.query, nil)
+			req = req.WithContext(auth.WithUser(req.Context(), alice))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var res Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+
+			if tc.wantError != "" {
+				assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+				assert.Equal(t, tc.wantError, res.Error)
+				assert.Equal(t, resp.CodeValidationFailed, res.Code)
+				require.Len(t, res.Fields, 1)
+				assert.Equal(t, tc.wantField, res.Fields[0].Field)
+				return
+			}
+
+			aliases := make([]string, 0, len(res.Items))
+			for _, item := range res.Items {
+				aliases = append(aliases, item.Alias)
+				assert.NotNil(t, item.CreatedAt)
+			}
+			assert.Equal(t, "OK", res.Status)
+			assert.Equal(t, tc.wantAliases, aliases)
+			assert.Equal(t, tc.wantTotal, res.Total)
+		})
+	}
+}
+
+func TestListHandlerAdminSeesAll(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := memory.New()
+	for i, alias := range []string{"alices", "bobs", "legacy"} {
+		_, err := urlStorage.SaveURL(ctx, storage.URL{Alias: alias, URL: "https://example.com", OwnerID: int64(i)})
+		require.NoError(t, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/url", nil)
+	req = req.WithContext(auth.WithUser(req.Context(), storage.User{ID: 10, Name: "admin", Role: storage.RoleAdmin}))
+
+	rr := httptest.NewRecorder()
+	New(slogdiscard.NewDiscardLogger(), urlStorage).ServeHTTP(rr, req)
+
+	var res Response
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.Equal(t, int64(3), res.Total)
+}
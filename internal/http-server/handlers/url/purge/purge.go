@@ -0,0 +1,45 @@
+package purge
+
+import (
+	"log/slog"
+	"net/http"
+
+	resp "url-shortener/internal/lib/api/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// CacheInvalidator drops an alias from the cache of resolved links
+type CacheInvalidator interface {
+	Invalidate(alias string)
+}
+
+// конструктор для handler, сбрасывающего алиас из кэша сервера.
+// Ссылки, изменённые командой `url-shortener link` в обход сервера,
+// иначе отдаются из кэша до истечения cache.ttl.
+func New(log *slog.Logger, cache CacheInvalidator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.purge.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeBadRequest, "invalid request"))
+			return
+		}
+
+		// ссылки может уже не быть в хранилище, поэтому не проверяем её
+		cache.Invalidate(alias)
+
+		log.Info("alias dropped from cache", slog.String("alias", alias))
+		render.JSON(w, r, resp.OK())
+	}
+}
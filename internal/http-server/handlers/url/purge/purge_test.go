@@ -0,0 +1,50 @@
+package purge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/cache"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeHandler(t *testing.T) {
+	ctx := context.Background()
+
+	// backend меняется в обход кэша, как это делает команда link
+	backend := memory.New()
+	_, err := backend.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	require.NoError(t, err)
+
+	urlCache := cache.New(backend, 10, time.Hour, time.Hour)
+	_, err = urlCache.GetURL(ctx, "google")
+	require.NoError(t, err)
+
+	require.NoError(t, backend.DeleteURL(ctx, "google"))
+	_, err = urlCache.GetURL(ctx, "google")
+	require.NoError(t, err, "the deleted link is still cached")
+
+	r := chi.NewRouter()
+	r.Delete("/url/{alias}/cache", New(slogdiscard.NewDiscardLogger(), urlCache))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/url/google/cache", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	_, err = urlCache.GetURL(ctx, "google")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// алиас, которого нет в кэше, тоже не ошибка
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/url/missing/cache", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -3,6 +3,7 @@
 package mocks
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -13,40 +14,51 @@ import (
 type URLSaverMock struct {
 	t *testing.T
 	// SaveURLFunc allows setting custom behavior for SaveURL method
-	SaveURLFunc func(urlToSave, alias string) (int64, error)
+	SaveURLFunc func(u storage.URL) (int64, error)
+	// GetURLByTargetFunc allows setting custom behavior for GetURLByTarget method
+	GetURLByTargetFunc func(ownerID int64, target string) (storage.URL, error)
 }
 
 // NewURLSaverMock creates a new mock instance
 func NewURLSaverMock(t *testing.T) *URLSaverMock {
 	return &URLSaverMock{
 		t: t,
-		SaveURLFunc: func(urlToSave, alias string) (int64, error) {
+		SaveURLFunc: func(u storage.URL) (int64, error) {
 			t.Errorf("SaveURL was called but not mocked")
 			return 0, errors.New("not mocked")
 		},
+		GetURLByTargetFunc: func(ownerID int64, target string) (storage.URL, error) {
+			t.Errorf("GetURLByTarget was called but not mocked")
+			return storage.URL{}, errors.New("not mocked")
+		},
 	}
 }
 
 // SaveURL calls the mocked function
-func (m *URLSaverMock) SaveURL(urlToSave, alias string) (int64, error) {
-	return m.SaveURLFunc(urlToSave, alias)
+func (m *URLSaverMock) SaveURL(_ context.Context, u storage.URL) (int64, error) {
+	return m.SaveURLFunc(u)
+}
+
+// GetURLByTarget calls the mocked function
+func (m *URLSaverMock) GetURLByTarget(_ context.Context, ownerID int64, target string) (storage.URL, error) {
+	return m.GetURLByTargetFunc(ownerID, target)
 }
 
 // Helper methods for common scenarios
 func (m *URLSaverMock) SetSaveURLSuccess(id int64) {
-	m.SaveURLFunc = func(urlToSave, alias string) (int64, error) {
+	m.SaveURLFunc = func(u storage.URL) (int64, error) {
 		return id, nil
 	}
 }
 
 func (m *URLSaverMock) SetSaveURLError(err error) {
-	m.SaveURLFunc = func(urlToSave, alias string) (int64, error) {
+	m.SaveURLFunc = func(u storage.URL) (int64, error) {
 		return 0, err
 	}
 }
 
 func (m *URLSaverMock) SetSaveURLExistsError() {
-	m.SaveURLFunc = func(urlToSave, alias string) (int64, error) {
+	m.SaveURLFunc = func(u storage.URL) (int64, error) {
 		return 0, storage.ErrURLExists
 	}
 }
@@ -1,13 +1,16 @@
 package save
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"url-shortener/internal/http-server/middleware/auth"
 	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/sl"
-	"url-shortener/internal/lib/random"
 	"url-shortener/internal/storage"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -18,27 +21,64 @@ import (
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
 	Alias string `json:"alias,omitempty"`
+	// RedirectCode overrides the server default redirect status for this link
+	RedirectCode int `json:"redirect_code,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	// ExpiresAt and TTL limit the link lifetime, only one of them may be set
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// TTL is counted from the moment of saving, e.g. "90m" or "720h"
+	TTL string `json:"ttl,omitempty"`
 }
 
 type Response struct {
 	resp.Response
-	Alias string `json:"alias,omitempty"`
+	Alias     string     `json:"alias,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Reused is set when an existing link to the same url was returned
+	Reused bool `json:"reused,omitempty"`
 }
 
-// TODO: move to config (or to DB)
-const aliasLenght = 6
-
 // go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLSaver
 type URLSaver interface {
-	SaveURL(urlToSave, alias string) (int64, error)
+	SaveURL(ctx context.Context, u storage.URL) (int64, error)
+	GetURLByTarget(ctx context.Context, ownerID int64, target string) (storage.URL, error)
+}
+
+// AliasGenerator makes aliases for links saved without one
+type AliasGenerator interface {
+	Generate() (string, error)
+}
+
+// AliasPolicy decides which aliases may be used
+type AliasPolicy interface {
+	// Normalize applies case folding to an alias given by a user
+	Normalize(alias string) string
+	// Check returns the broken rule and its parameter, or an empty rule
+	Check(alias string) (rule, param string)
+	// Allowed reports whether a generated alias may be used
+	Allowed(alias string) bool
+}
+
+// CollisionObserver is implemented by generators that adapt to collisions,
+// it is told whether each generated alias turned out to be taken
+type CollisionObserver interface {
+	Observe(collided bool)
 }
 
-// конструктор для handler, будет вызываться при подклчении к роутеру
-func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+// конструктор для handler, будет вызываться при подклчении к роутеру.
+// С reuseExisting ссылка без алиаса и срока действия на уже сохранённый
+// пользователем адрес не создаётся заново, возвращается существующая.
+func New(log *slog.Logger, urlSaver URLSaver, aliases AliasGenerator, policy AliasPolicy, reuseExisting bool) http.HandlerFunc {
+	validate := NewValidator(policy)
+
+	observe := func(bool) {}
+	if o, ok := aliases.(CollisionObserver); ok {
+		observe = o.Observe
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
 
-		log = log.With(
+		log := log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
@@ -49,34 +89,89 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 		if err != nil {
 			log.Error("failed to decode request body", sl.Err(err))
 
-			render.JSON(w, r, resp.Error("failed to decode request"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeBadRequest, "failed to decode request"))
 
 			return
 		}
 
 		log.Info("request body decoded", slog.Any("request", req))
 
+		req.Alias = policy.Normalize(req.Alias)
+
 		// валидация
-		if err := validator.New().Struct(req); err != nil {
+		if err := validate.Struct(req); err != nil {
 			validateErr := err.(validator.ValidationErrors)
 
 			log.Error("invalid request", sl.Err(err))
 
-			// render.JSON(w, r, resp.Error("invalid request"))
+			render.Status(r, http.StatusUnprocessableEntity)
 			render.JSON(w, r, resp.ValidationError(validateErr))
 
 			return
 		}
 
-		alias := req.Alias
-		if alias == "" {
-			alias = random.NewRandomString(aliasLenght)
+		expiresAt, fieldErr := req.Expiry(time.Now())
+		if fieldErr != nil {
+			log.Info("invalid expiry", slog.String("error", fieldErr.Message))
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, resp.FieldsError(*fieldErr))
+
+			return
+		}
+
+		link := storage.URL{
+			Alias:        req.Alias,
+			URL:          req.URL,
+			RedirectCode: req.RedirectCode,
+			ExpiresAt:    expiresAt,
+		}
+		if user, ok := auth.UserFromContext(r.Context()); ok {
+			link.OwnerID = user.ID
+		}
+
+		if reuseExisting && req.Alias == "" && expiresAt.IsZero() {
+			existing, err := urlSaver.GetURLByTarget(r.Context(), link.OwnerID, link.URL)
+			switch {
+			case err == nil && existing.RedirectCode == link.RedirectCode:
+				log.Info("existing url reused", slog.String("alias", existing.Alias))
+				render.JSON(w, r, Response{
+					Response: resp.OK(),
+					Alias:    existing.Alias,
+					Reused:   true,
+				})
+				return
+			case err != nil && !errors.Is(err, storage.ErrURLNotFound):
+				// без поиска просто создаём новую ссылку
+				log.Error("failed to look up existing url", sl.Err(err))
+			}
 		}
 
-		id, err := urlSaver.SaveURL(req.URL, alias)
+		if link.Alias == "" {
+			if link.Alias, err = GenerateAlias(aliases, policy); err != nil {
+				log.Error("failed to generate alias", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to generate alias"))
+				return
+			}
+		}
+
+		id, err := urlSaver.SaveURL(r.Context(), link)
+		if req.Alias == "" && (err == nil || errors.Is(err, storage.ErrURLExists)) {
+			observe(err != nil)
+		}
 		if err == nil {
 			log.Info("url added", slog.Int64("id", id))
-			responseOK(w, r, alias)
+			responseOK(w, r, link)
+			return
+		}
+
+		// владелец ключа удалён после проверки ключа, как и в auth
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Info("owner of the link does not exist", slog.Int64("owner_id", link.OwnerID))
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error(resp.CodeUnauthorized, "invalid api key"))
 			return
 		}
 
@@ -84,42 +179,128 @@ func New(log *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 		if errors.Is(err, storage.ErrURLExists) {
 			// если алиас задан пользователем — сразу конфликт
 			if req.Alias != "" {
-				log.Info("alias already in use", slog.String("alias", alias))
-				render.JSON(w, r, resp.Error("alias already exists"))
+				log.Info("alias already in use", slog.String("alias", link.Alias))
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error(resp.CodeAliasExists, "alias already exists"))
 				return
 			}
 
 			// если алиас сгенерирован — пробуем несколько раз
 			for attempt := 1; attempt <= 4; attempt++ {
-				alias = random.NewRandomString(aliasLenght)
-				if id, err = urlSaver.SaveURL(req.URL, alias); err == nil {
-					log.Info("url saved after retry", slog.Int64("id", id), slog.String("alias", alias), slog.Int("attempt", attempt))
-					responseOK(w, r, alias)
+				if link.Alias, err = GenerateAlias(aliases, policy); err != nil {
+					log.Error("failed to generate alias", sl.Err(err))
+					render.Status(r, http.StatusInternalServerError)
+					render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to generate alias"))
+					return
+				}
+				id, err = urlSaver.SaveURL(r.Context(), link)
+				if err == nil || errors.Is(err, storage.ErrURLExists) {
+					observe(err != nil)
+				}
+				if err == nil {
+					log.Info("url saved after retry", slog.Int64("id", id), slog.String("alias", link.Alias), slog.Int("attempt", attempt))
+					responseOK(w, r, link)
 					return
 				}
 				if !errors.Is(err, storage.ErrURLExists) {
 					log.Error("failed to save url", sl.Err(err))
-					render.JSON(w, r, resp.Error("failed to save url"))
+					render.Status(r, http.StatusInternalServerError)
+					render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to save url"))
 					return
 				}
-				log.Info("generated alias collision, retrying", slog.String("alias", alias), slog.Int("attempt", attempt))
+				log.Info("generated alias collision, retrying", slog.String("alias", link.Alias), slog.Int("attempt", attempt))
 			}
 			// Exhausted attempts to generate a unique alias
 			log.Error("could not generate unique alias")
-			render.JSON(w, r, resp.Error("could not generate unique alias"))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "could not generate unique alias"))
 			return
 		}
 
 		// Unexpected storage error
 		log.Error("failed to save url", sl.Err(err))
-		render.JSON(w, r, resp.Error("failed to save url"))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to save url"))
 		return
 	}
 }
 
-func responseOK(w http.ResponseWriter, r *http.Request, alias string) {
-	render.JSON(w, r, Response{
+// NewValidator returns a validator of requests that checks the alias
+// chosen by the user against the policy
+func NewValidator(policy AliasPolicy) *validator.Validate {
+	validate := resp.NewValidator()
+	validate.RegisterStructValidation(func(sl validator.StructLevel) {
+		req := sl.Current().Interface().(Request)
+		if req.Alias == "" {
+			return
+		}
+		if rule, param := policy.Check(req.Alias); rule != "" {
+			sl.ReportError(req.Alias, "alias", "Alias", rule, param)
+		}
+	}, Request{})
+
+	return validate
+}
+
+// GenerateAlias returns a generated alias that is not reserved or blocked
+func GenerateAlias(aliases AliasGenerator, policy AliasPolicy) (string, error) {
+	const op = "handlers.url.save.GenerateAlias"
+
+	for attempt := 0; attempt < 10; attempt++ {
+		alias, err := aliases.Generate()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		if policy.Allowed(alias) {
+			return alias, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: every generated alias is reserved or blocked", op)
+}
+
+// Expiry returns the moment the link expires, zero if it never does
+func (req Request) Expiry(now time.Time) (time.Time, *resp.FieldError) {
+	switch {
+	case req.ExpiresAt != nil && req.TTL != "":
+		return time.Time{}, &resp.FieldError{
+			Field:   "ttl",
+			Rule:    "excluded_with",
+			Param:   "expires_at",
+			Message: "only one of expires_at and ttl may be set",
+		}
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			return time.Time{}, &resp.FieldError{
+				Field:   "expires_at",
+				Rule:    "future",
+				Message: "expires_at must be in the future",
+			}
+		}
+		return *req.ExpiresAt, nil
+	case req.TTL != "":
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return time.Time{}, &resp.FieldError{
+				Field:   "ttl",
+				Rule:    "duration",
+				Message: "ttl must be a positive duration, e.g. 90m or 720h",
+			}
+		}
+		return now.Add(ttl), nil
+	}
+
+	return time.Time{}, nil
+}
+
+func responseOK(w http.ResponseWriter, r *http.Request, link storage.URL) {
+	res := Response{
 		Response: resp.OK(),
-		Alias:    alias,
-	})
+		Alias:    link.Alias,
+	}
+	if !link.ExpiresAt.IsZero() {
+		res.ExpiresAt = &link.ExpiresAt
+	}
+
+	render.JSON(w, r, res)
 }
@@ -3,11 +3,15 @@ package save
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/lib/alias"
+	resp "url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
 	"url-shortener/internal/storage"
 )
@@ -19,8 +23,18 @@ func TestSaveHandler(t *testing.T) {
 		name           string
 		request        Request
 		mockSetup      func(*mocks.URLSaverMock)
+		aliases        AliasGenerator
+		policy         AliasPolicy
+		reuseExisting  bool
+		expectedReused bool
 		expectedStatus int
 		expectedError  string
+		expectedCode   string
+		expectedField  string
+		// expectedLength of a generated alias, 6 if not set
+		expectedLength int
+		// expectedAlias in the response, the requested one if not set
+		expectedAlias string
 	}{
 		{
 			name: "Success with custom alias",
@@ -53,8 +67,22 @@ func TestSaveHandler(t *testing.T) {
 			mockSetup: func(m *mocks.URLSaverMock) {
 				m.SetSaveURLExistsError()
 			},
-			expectedStatus: http.StatusOK,
+			expectedStatus: http.StatusConflict,
 			expectedError:  "alias already exists",
+			expectedCode:   resp.CodeAliasExists,
+		},
+		{
+			name: "Owner does not exist",
+			request: Request{
+				URL:   "https://google.com",
+				Alias: "test_alias",
+			},
+			mockSetup: func(m *mocks.URLSaverMock) {
+				m.SetSaveURLError(storage.ErrUserNotFound)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid api key",
+			expectedCode:   resp.CodeUnauthorized,
 		},
 		{
 			name: "Alias collision with auto-generated (retry success)",
@@ -64,7 +92,7 @@ func TestSaveHandler(t *testing.T) {
 			},
 			mockSetup: func(m *mocks.URLSaverMock) {
 				callCount := 0
-				m.SaveURLFunc = func(urlToSave, alias string) (int64, error) {
+				m.SaveURLFunc = func(u storage.URL) (int64, error) {
 					callCount++
 					if callCount == 1 {
 						return 0, storage.ErrURLExists // First call fails
@@ -74,6 +102,248 @@ func TestSaveHandler(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name: "Collisions grow the alias length",
+			request: Request{
+				URL: "https://google.com",
+			},
+			aliases: alias.NewAdaptive(log, 6, 8, func(length int) alias.Generator { return alias.NewRandom(length) }),
+			mockSetup: func(m *mocks.URLSaverMock) {
+				callCount := 0
+				m.SaveURLFunc = func(u storage.URL) (int64, error) {
+					callCount++
+					if callCount <= 3 {
+						return 0, storage.ErrURLExists
+					}
+					return 1, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedLength: 7,
+		},
+		{
+			name: "Existing url is reused",
+			request: Request{
+				URL: "https://google.com",
+			},
+			reuseExisting: true,
+			mockSetup: func(m *mocks.URLSaverMock) {
+				m.GetURLByTargetFunc = func(ownerID int64, target string) (storage.URL, error) {
+					return storage.URL{ID: 7, Alias: "exists", URL: "https://Google.com"}, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedReused: true,
+		},
+		{
+			name: "Existing url with another redirect code is not reused",
+			request: Request{
+				URL:          "https://google.com",
+				RedirectCode: http.StatusMovedPermanently,
+			},
+			reuseExisting: true,
+			mockSetup: func(m *mocks.URLSaverMock) {
+				m.GetURLByTargetFunc = func(ownerID int64, target string) (storage.URL, error) {
+					return storage.URL{ID: 7, Alias: "exists", URL: "https://google.com"}, nil
+				}
+				m.SetSaveURLSuccess(8)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "New url is saved in reuse mode",
+			request: Request{
+				URL: "https://google.com",
+			},
+			reuseExisting: true,
+			mockSetup: func(m *mocks.URLSaverMock) {
+				m.GetURLByTargetFunc = func(ownerID int64, target string) (storage.URL, error) {
+					return storage.URL{}, storage.ErrURLNotFound
+				}
+				m.SetSaveURLSuccess(1)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Custom alias skips reuse",
+			request: Request{
+				URL:   "https://google.com",
+				Alias: "own_alias",
+			},
+			reuseExisting: true,
+			mockSetup: func(m *mocks.URLSaverMock) {
+				m.SetSaveURLSuccess(1)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Generated alias is saved",
+			request: Request{
+				URL: "https://google.com",
+			},
+			aliases: alias.NewSequential(alias.NewCounter(0), 6),
+			mockSetup: func(m *mocks.URLSaverMock) {
+				m.SaveURLFunc = func(u storage.URL) (int64, error) {
+					if u.Alias != "000001" {
+						t.Errorf("expected alias %q, got %q", "000001", u.Alias)
+					}
+					return 1, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Alias generator fails",
+			request: Request{
+				URL: "https://google.com",
+			},
+			aliases:        failingGenerator{},
+			mockSetup:      func(m *mocks.URLSaverMock) {},
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  "failed to generate alias",
+			expectedCode:   resp.CodeInternal,
+		},
+		{
+			name: "Custom redirect code",
+			request: Request{
+				URL:          "https://google.com",
+				Alias:        "seo_alias",
+				RedirectCode: http.StatusMovedPermanently,
+			},
+			mockSetup: func(m *mocks.URLSaverMock) {
+				m.SaveURLFunc = func(u storage.URL) (int64, error) {
+					if u.RedirectCode != http.StatusMovedPermanently {
+						t.Errorf("expected redirect code %d, got %d", http.StatusMovedPermanently, u.RedirectCode)
+					}
+					return 1, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Invalid redirect code",
+			request: Request{
+				URL:          "https://google.com",
+				Alias:        "test_alias",
+				RedirectCode: http.StatusOK,
+			},
+			mockSetup:      func(m *mocks.URLSaverMock) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "field redirect_code must be one of 301, 302, 307, 308",
+			expectedCode:   resp.CodeValidationFailed,
+			expectedField:  "redirect_code",
+		},
+		{
+			name: "Success with ttl",
+			request: Request{
+				URL:   "https://google.com",
+				Alias: "campaign",
+				TTL:   "24h",
+			},
+			mockSetup: func(m *mocks.URLSaverMock) {
+				m.SaveURLFunc = func(u storage.URL) (int64, error) {
+					if u.ExpiresAt.IsZero() {
+						t.Errorf("expected expires_at to be set from ttl")
+					}
+					return 1, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Both expires_at and ttl",
+			request: Request{
+				URL:       "https://google.com",
+				Alias:     "campaign",
+				ExpiresAt: timePtr(time.Now().Add(time.Hour)),
+				TTL:       "24h",
+			},
+			mockSetup:      func(m *mocks.URLSaverMock) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "only one of expires_at and ttl may be set",
+			expectedCode:   resp.CodeValidationFailed,
+			expectedField:  "ttl",
+		},
+		{
+			name: "Expires_at in the past",
+			request: Request{
+				URL:       "https://google.com",
+				Alias:     "campaign",
+				ExpiresAt: timePtr(time.Now().Add(-time.Hour)),
+			},
+			mockSetup:      func(m *mocks.URLSaverMock) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "expires_at must be in the future",
+			expectedCode:   resp.CodeValidationFailed,
+			expectedField:  "expires_at",
+		},
+		{
+			name: "Invalid ttl",
+			request: Request{
+				URL:   "https://google.com",
+				Alias: "campaign",
+				TTL:   "tomorrow",
+			},
+			mockSetup:      func(m *mocks.URLSaverMock) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "ttl must be a positive duration, e.g. 90m or 720h",
+			expectedCode:   resp.CodeValidationFailed,
+			expectedField:  "ttl",
+		},
+		{
+			name: "Alias with a slash",
+			request: Request{
+				URL:   "https://google.com",
+				Alias: "a/b",
+			},
+			mockSetup:      func(m *mocks.URLSaverMock) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  `field alias must not contain "/"`,
+			expectedCode:   resp.CodeValidationFailed,
+			expectedField:  "alias",
+		},
+		{
+			name: "Alias too short",
+			request: Request{
+				URL:   "https://google.com",
+				Alias: "ab",
+			},
+			mockSetup:      func(m *mocks.URLSaverMock) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "field alias must be at least 3 characters long",
+			expectedCode:   resp.CodeValidationFailed,
+			expectedField:  "alias",
+		},
+		{
+			name: "Reserved alias",
+			request: Request{
+				URL:   "https://google.com",
+				Alias: "URL",
+			},
+			mockSetup:      func(m *mocks.URLSaverMock) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "field alias is reserved",
+			expectedCode:   resp.CodeValidationFailed,
+			expectedField:  "alias",
+		},
+		{
+			name: "Alias is case folded",
+			request: Request{
+				URL:   "https://google.com",
+				Alias: "MyLink",
+			},
+			policy: testPolicy(true),
+			mockSetup: func(m *mocks.URLSaverMock) {
+				m.SaveURLFunc = func(u storage.URL) (int64, error) {
+					if u.Alias != "mylink" {
+						t.Errorf("expected alias %q, got %q", "mylink", u.Alias)
+					}
+					return 1, nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedAlias:  "mylink",
+		},
 		{
 			name: "Invalid URL",
 			request: Request{
@@ -81,8 +351,10 @@ func TestSaveHandler(t *testing.T) {
 				Alias: "test_alias",
 			},
 			mockSetup:      func(m *mocks.URLSaverMock) {},
-			expectedStatus: http.StatusOK,
-			expectedError:  "field URL is not a valid URL",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "field url is not a valid URL",
+			expectedCode:   resp.CodeValidationFailed,
+			expectedField:  "url",
 		},
 		{
 			name: "Empty URL",
@@ -91,8 +363,10 @@ func TestSaveHandler(t *testing.T) {
 				Alias: "test_alias",
 			},
 			mockSetup:      func(m *mocks.URLSaverMock) {},
-			expectedStatus: http.StatusOK,
-			expectedError:  "field URL is not valid",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedError:  "field url is a required field",
+			expectedCode:   resp.CodeValidationFailed,
+			expectedField:  "url",
 		},
 	}
 
@@ -103,7 +377,15 @@ func TestSaveHandler(t *testing.T) {
 			tt.mockSetup(mockURLSaver)
 
 			// Create handler
-			handler := New(log, mockURLSaver)
+			aliases := tt.aliases
+			if aliases == nil {
+				aliases = alias.NewRandom(6)
+			}
+			policy := tt.policy
+			if policy == nil {
+				policy = testPolicy(false)
+			}
+			handler := New(log, mockURLSaver, aliases, policy, tt.reuseExisting)
 
 			// Prepare request
 			reqBody, _ := json.Marshal(tt.request)
@@ -135,22 +417,59 @@ func TestSaveHandler(t *testing.T) {
 				if response.Status != "Error" || response.Error != tt.expectedError {
 					t.Errorf("expected error %q, got status=%q error=%q", tt.expectedError, response.Status, response.Error)
 				}
+				if response.Code != tt.expectedCode {
+					t.Errorf("expected code %q, got %q", tt.expectedCode, response.Code)
+				}
+				if tt.expectedField != "" && (len(response.Fields) != 1 || response.Fields[0].Field != tt.expectedField) {
+					t.Errorf("expected a single error for field %q, got %+v", tt.expectedField, response.Fields)
+				}
 			} else {
 				if response.Status != "OK" {
 					t.Errorf("expected status OK, got %q", response.Status)
 				}
+				if response.Reused != tt.expectedReused {
+					t.Errorf("expected reused %v, got %v", tt.expectedReused, response.Reused)
+				}
 				if tt.request.Alias == "" {
 					// For auto-generated alias, check it's generated
-					if response.Alias == "" || len(response.Alias) != aliasLenght {
-						t.Errorf("expected auto-generated alias of length %d, got %q", aliasLenght, response.Alias)
+					wantLength := 6
+					if tt.expectedLength != 0 {
+						wantLength = tt.expectedLength
+					}
+					if len(response.Alias) != wantLength {
+						t.Errorf("expected auto-generated alias of length %d, got %q", wantLength, response.Alias)
 					}
 				} else {
 					// For custom alias, check it matches
-					if response.Alias != tt.request.Alias {
-						t.Errorf("expected alias %q, got %q", tt.request.Alias, response.Alias)
+					wantAlias := tt.request.Alias
+					if tt.expectedAlias != "" {
+						wantAlias = tt.expectedAlias
+					}
+					if response.Alias != wantAlias {
+						t.Errorf("expected alias %q, got %q", wantAlias, response.Alias)
 					}
 				}
 			}
 		})
 	}
 }
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+type failingGenerator struct{}
+
+func (failingGenerator) Generate() (string, error) {
+	return "", errors.New("no entropy")
+}
+
+func testPolicy(foldCase bool) *alias.Policy {
+	return alias.NewPolicy(alias.Rules{
+		MinLength: 3,
+		MaxLength: 32,
+		Charset:   "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_",
+		FoldCase:  foldCase,
+		Reserved:  []string{"url"},
+	})
+}
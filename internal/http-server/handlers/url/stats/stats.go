@@ -0,0 +1,107 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultDays = 30
+	maxDays     = 366
+)
+
+// go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ClickStatsGetter
+type ClickStatsGetter interface {
+	ClickStats(ctx context.Context, alias string, since time.Time) (storage.ClickStats, error)
+}
+
+type Response struct {
+	resp.Response
+	Alias string     `json:"alias"`
+	Total int64      `json:"total"`
+	Daily []DayCount `json:"daily"`
+}
+
+type DayCount struct {
+	Day   string `json:"day"`
+	Count int64  `json:"count"`
+}
+
+// конструктор для handler статистики переходов по ссылке.
+// ?days=N ограничивает посуточную статистику последними N днями
+func New(log *slog.Logger, statsGetter ClickStatsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.stats.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeBadRequest, "invalid request"))
+			return
+		}
+
+		days := defaultDays
+		if raw := r.URL.Query().Get("days"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxDays {
+				log.Info("invalid days", slog.String("days", raw))
+				render.Status(r, http.StatusUnprocessableEntity)
+				render.JSON(w, r, resp.FieldsError(resp.FieldError{
+					Field:   "days",
+					Rule:    "range",
+					Param:   "1-366",
+					Message: "days must be a number from 1 to 366",
+				}))
+				return
+			}
+			days = n
+		}
+
+		// считаем с начала суток (UTC), чтобы первый день не был обрезан
+		since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+
+		stats, err := statsGetter.ClickStats(r.Context(), alias, since)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get click stats", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+			return
+		}
+
+		res := Response{
+			Response: resp.OK(),
+			Alias:    alias,
+			Total:    stats.Total,
+			Daily:    make([]DayCount, 0, len(stats.Daily)),
+		}
+		for _, d := range stats.Daily {
+			res.Daily = append(res.Daily, DayCount{Day: d.Day, Count: d.Count})
+		}
+
+		render.JSON(w, r, res)
+	}
+}
@@ -0,0 +1,82 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := memory.New()
+	id, err := urlStorage.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	require.NoError(t, urlStorage.SaveClicks(ctx, []storage.Click{
+		{URLID: id, ClickedAt: now},
+		{URLID: id, ClickedAt: now},
+		{URLID: id, ClickedAt: now.AddDate(0, 0, -1)},
+		{URLID: id, ClickedAt: now.AddDate(0, 0, -100)},
+	}))
+
+	r := chi.NewRouter()
+	r.Get("/url/{alias}/stats", New(slogdiscard.NewDiscardLogger(), urlStorage))
+
+	cases := []struct {
+		name      string
+		path      string
+		wantTotal int64
+		wantDaily []DayCount
+		wantError string
+	}{
+		{
+			name:      "Default period",
+			path:      "/url/google/stats",
+			wantTotal: 4,
+			wantDaily: []DayCount{
+				{Day: now.AddDate(0, 0, -1).Format("2006-01-02"), Count: 1},
+				{Day: now.Format("2006-01-02"), Count: 2},
+			},
+		},
+		{
+			name:      "Today only",
+			path:      "/url/google/stats?days=1",
+			wantTotal: 4,
+			wantDaily: []DayCount{{Day: now.Format("2006-01-02"), Count: 2}},
+		},
+		{name: "Invalid days", path: "/url/google/stats?days=0", wantError: "days must be a number from 1 to 366"},
+		{name: "Not found", path: "/url/missing/stats", wantError: "not found"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			var res Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+
+			if tc.wantError != "" {
+				assert.Equal(t, tc.wantError, res.Error)
+				return
+			}
+
+			assert.Equal(t, "OK", res.Status)
+			assert.Equal(t, tc.wantTotal, res.Total)
+			assert.Equal(t, tc.wantDaily, res.Daily)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	URL string `json:"url" validate:"required,url"`
+}
+
+// go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLUpdater
+type URLUpdater interface {
+	GetURL(ctx context.Context, alias string) (storage.URL, error)
+	UpdateURL(ctx context.Context, alias, newURL string) error
+}
+
+// конструктор для handler, меняющего адрес, на который ведёт алиас
+func New(log *slog.Logger, urlUpdater URLUpdater) http.HandlerFunc {
+	validate := resp.NewValidator()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeBadRequest, "invalid request"))
+			return
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(resp.CodeBadRequest, "failed to decode request"))
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			log.Info("invalid request", sl.Err(err))
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, resp.ValidationError(err.(validator.ValidationErrors)))
+			return
+		}
+
+		u, err := urlUpdater.GetURL(r.Context(), alias)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to update url"))
+			return
+		}
+
+		if !auth.CanManage(r.Context(), u.OwnerID) {
+			log.Info("link belongs to another user", slog.String("alias", alias))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(resp.CodeForbidden, "not allowed to manage this link"))
+			return
+		}
+
+		err = urlUpdater.UpdateURL(r.Context(), alias, req.URL)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			log.Info("url not found", slog.String("alias", alias))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error(resp.CodeNotFound, "not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update url", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error(resp.CodeInternal, "failed to update url"))
+			return
+		}
+
+		log.Info("url updated", slog.String("alias", alias))
+		render.JSON(w, r, resp.OK())
+	}
+}
@@ -0,0 +1,69 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateHandler(t *testing.T) {
+	ctx := context.Background()
+
+	urlStorage := memory.New()
+	_, err := urlStorage.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com", OwnerID: 1})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Patch("/url/{alias}", New(slogdiscard.NewDiscardLogger(), urlStorage))
+
+	alice := storage.User{ID: 1, Name: "alice"}
+	bob := storage.User{ID: 2, Name: "bob"}
+
+	cases := []struct {
+		name       string
+		alias      string
+		body       string
+		user       storage.User
+		wantStatus int
+		wantError  string
+	}{
+		{name: "Other user", alias: "google", body: `{"url": "https://evil.example.com"}`, user: bob, wantStatus: http.StatusForbidden, wantError: "not allowed to manage this link"},
+		{name: "Success", alias: "google", body: `{"url": "https://www.google.ru"}`, user: alice, wantStatus: http.StatusOK},
+		{name: "Invalid URL", alias: "google", body: `{"url": "not a url"}`, user: alice, wantStatus: http.StatusUnprocessableEntity, wantError: "field url is not a valid URL"},
+		{name: "Not found", alias: "missing", body: `{"url": "https://example.com"}`, user: alice, wantStatus: http.StatusNotFound, wantError: "not found"},
+		{name: "Bad body", alias: "google", body: `{`, user: alice, wantStatus: http.StatusBadRequest, wantError: "failed to decode request"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPatch, "/url/"+tc.alias, strings.NewReader(tc.body))
+			req = req.WithContext(auth.WithUser(req.Context(), tc.user))
+			r.ServeHTTP(rr, req)
+
+			var res struct {
+				Status string `json:"status"`
+				Error  string `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantError, res.Error)
+		})
+	}
+
+	u, err := urlStorage.GetURL(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.google.ru", u.URL)
+}
@@ -0,0 +1,204 @@
+// Package auth authenticates clients by api keys sent as
+// "Authorization: Bearer <key>".
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"url-shortener/internal/http-server/middleware/logger"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=KeyGetter
+type KeyGetter interface {
+	GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error)
+	GetUser(ctx context.Context, id int64) (storage.User, error)
+}
+
+type (
+	keyCtx  struct{}
+	userCtx struct{}
+)
+
+// KeyFromContext returns the key the request was authenticated with
+func KeyFromContext(ctx context.Context) (storage.APIKey, bool) {
+	k, ok := ctx.Value(keyCtx{}).(storage.APIKey)
+	return k, ok
+}
+
+// WithKey returns a context of a request authenticated with the key
+func WithKey(ctx context.Context, k storage.APIKey) context.Context {
+	return context.WithValue(ctx, keyCtx{}, k)
+}
+
+// UserFromContext returns the owner of the key the request was authenticated with
+func UserFromContext(ctx context.Context) (storage.User, bool) {
+	u, ok := ctx.Value(userCtx{}).(storage.User)
+	return u, ok
+}
+
+// WithUser returns a context of a request made by the user
+func WithUser(ctx context.Context, u storage.User) context.Context {
+	return context.WithValue(ctx, userCtx{}, u)
+}
+
+// CanManage reports whether the user of the request may change or delete
+// a link of the given owner: admins may manage any link, others only their own
+func CanManage(ctx context.Context, ownerID int64) bool {
+	u, ok := UserFromContext(ctx)
+	if !ok {
+		return false
+	}
+	return u.IsAdmin() || (ownerID != 0 && u.ID == ownerID)
+}
+
+// New rejects requests without a valid active key with 401 and puts
+// the key and its user into the request context
+func New(log *slog.Logger, keys KeyGetter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log = log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(slog.String("request_id", middleware.GetReqID(r.Context())))
+
+			token, ok := bearerToken(r)
+			if !ok {
+				log.Info("missing api key")
+				unauthorized(w, r, "api key required")
+				return
+			}
+
+			key, err := keys.GetAPIKeyByHash(r.Context(), apikey.Hash(token))
+			if errors.Is(err, storage.ErrAPIKeyNotFound) {
+				log.Info("unknown api key", slog.String("prefix", apikey.Prefix(token)))
+				unauthorized(w, r, "invalid api key")
+				return
+			}
+			if err != nil {
+				log.Error("failed to get api key", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+				return
+			}
+			if key.Revoked() {
+				log.Info("revoked api key", slog.Int64("key_id", key.ID))
+				unauthorized(w, r, "invalid api key")
+				return
+			}
+
+			user, err := keys.GetUser(r.Context(), key.UserID)
+			if errors.Is(err, storage.ErrUserNotFound) {
+				log.Info("api key has no user", slog.Int64("key_id", key.ID))
+				unauthorized(w, r, "invalid api key")
+				return
+			}
+			if err != nil {
+				log.Error("failed to get user", sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error(resp.CodeInternal, "internal error"))
+				return
+			}
+
+			logger.AddAttrs(r.Context(),
+				slog.Int64("key_id", key.ID),
+				slog.Int64("user_id", user.ID),
+				slog.String("role", user.Role),
+			)
+			log.Debug("request authenticated",
+				slog.Int64("key_id", key.ID),
+				slog.String("key_name", key.Name),
+				slog.Int64("user_id", user.ID),
+				slog.String("role", user.Role),
+			)
+
+			ctx := WithUser(WithKey(r.Context(), key), user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// DeniedResponse is sent with 403 by RequireScope and RequireRole
+type DeniedResponse struct {
+	resp.Response
+	// RequiredScope or RequiredRole is what the request lacks
+	RequiredScope string `json:"required_scope,omitempty"`
+	RequiredRole  string `json:"required_role,omitempty"`
+	// Role is the role of the user who made the request
+	Role string `json:"role,omitempty"`
+}
+
+// RequireScope rejects requests whose key lacks the scope with 403.
+// It must be used after New.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key, ok := KeyFromContext(r.Context())
+			if !ok || !key.HasScope(scope) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, DeniedResponse{
+					Response:      resp.Error(resp.CodeForbidden, "api key lacks scope "+scope),
+					RequiredScope: scope,
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireRole rejects requests of users below the role with 403.
+// It must be used after New.
+func RequireRole(role string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if !ok || !user.HasRole(role) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, DeniedResponse{
+					Response:     resp.Error(resp.CodeForbidden, "role "+role+" required"),
+					RequiredRole: role,
+					Role:         user.Role,
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="url-shortener"`)
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, resp.Error(resp.CodeUnauthorized, msg))
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/lib/apikey"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth(t *testing.T) {
+	ctx := context.Background()
+	keys := memory.New()
+
+	userID, err := keys.SaveUser(ctx, storage.User{Name: "alice", Role: storage.RoleEditor})
+	require.NoError(t, err)
+
+	// newKey returns the key value and its id
+	newKey := func(scopes ...string) (string, int64) {
+		token, err := apikey.Generate()
+		require.NoError(t, err)
+		id, err := keys.SaveAPIKey(ctx, storage.APIKey{
+			UserID: userID,
+			Name:   "test",
+			Prefix: apikey.Prefix(token),
+			Hash:   apikey.Hash(token),
+			Scopes: scopes,
+		})
+		require.NoError(t, err)
+		return token, id
+	}
+
+	reader, _ := newKey(apikey.ScopeRead)
+	writer, _ := newKey(apikey.ScopeRead, apikey.ScopeWrite)
+	revoked, revokedID := newKey(apikey.ScopeRead, apikey.ScopeWrite)
+	require.NoError(t, keys.RevokeAPIKey(ctx, revokedID, time.Now()))
+
+	orphan, err := apikey.Generate()
+	require.NoError(t, err)
+	_, err = keys.SaveAPIKey(ctx, storage.APIKey{Name: "no user", Hash: apikey.Hash(orphan), Scopes: []string{apikey.ScopeRead}})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(New(slogdiscard.NewDiscardLogger(), keys))
+	r.With(RequireScope(apikey.ScopeRead)).Get("/", func(w http.ResponseWriter, r *http.Request) {
+		key, ok := KeyFromContext(r.Context())
+		assert.True(t, ok)
+		assert.NotZero(t, key.ID)
+		user, ok := UserFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "alice", user.Name)
+	})
+	r.With(RequireScope(apikey.ScopeWrite)).Post("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		name       string
+		method     string
+		header     string
+		wantStatus int
+	}{
+		{name: "No header", method: http.MethodGet, wantStatus: http.StatusUnauthorized},
+		{name: "Basic scheme", method: http.MethodGet, header: "Basic YWRtaW46YWRtaW4=", wantStatus: http.StatusUnauthorized},
+		{name: "Unknown key", method: http.MethodGet, header: "Bearer usk_nope", wantStatus: http.StatusUnauthorized},
+		{name: "Revoked key", method: http.MethodGet, header: "Bearer " + revoked, wantStatus: http.StatusUnauthorized},
+		{name: "Key without user", method: http.MethodGet, header: "Bearer " + orphan, wantStatus: http.StatusUnauthorized},
+		{name: "Read", method: http.MethodGet, header: "Bearer " + reader, wantStatus: http.StatusOK},
+		{name: "Lowercase scheme", method: http.MethodGet, header: "bearer " + reader, wantStatus: http.StatusOK},
+		{name: "Missing scope", method: http.MethodPost, header: "Bearer " + reader, wantStatus: http.StatusForbidden},
+		{name: "Write", method: http.MethodPost, header: "Bearer " + writer, wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			if tc.wantStatus == http.StatusUnauthorized {
+				assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestCanManage(t *testing.T) {
+	ctx := context.Background()
+
+	alice := WithUser(ctx, storage.User{ID: 1, Name: "alice"})
+	admin := WithUser(ctx, storage.User{ID: 2, Name: "admin", Role: storage.RoleAdmin})
+
+	assert.True(t, CanManage(alice, 1))
+	assert.False(t, CanManage(alice, 3))
+	assert.False(t, CanManage(alice, 0), "links without owner are managed by admins only")
+	assert.True(t, CanManage(admin, 3))
+	assert.True(t, CanManage(admin, 0))
+	assert.False(t, CanManage(ctx, 1), "anonymous requests manage nothing")
+}
+
+func TestRequireRole(t *testing.T) {
+	r := chi.NewRouter()
+	r.With(RequireRole(storage.RoleViewer)).Get("/stats", func(w http.ResponseWriter, r *http.Request) {})
+	r.With(RequireRole(storage.RoleEditor)).Post("/", func(w http.ResponseWriter, r *http.Request) {})
+	r.With(RequireRole(storage.RoleAdmin)).Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		name       string
+		role       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{name: "Viewer reads stats", role: storage.RoleViewer, method: http.MethodGet, path: "/stats", wantStatus: http.StatusOK},
+		{name: "Viewer creates", role: storage.RoleViewer, method: http.MethodPost, path: "/", wantStatus: http.StatusForbidden},
+		{name: "Editor creates", role: storage.RoleEditor, method: http.MethodPost, path: "/", wantStatus: http.StatusOK},
+		{name: "Editor reads stats", role: storage.RoleEditor, method: http.MethodGet, path: "/stats", wantStatus: http.StatusOK},
+		{name: "Editor lists users", role: storage.RoleEditor, method: http.MethodGet, path: "/users", wantStatus: http.StatusForbidden},
+		{name: "Admin lists users", role: storage.RoleAdmin, method: http.MethodGet, path: "/users", wantStatus: http.StatusOK},
+		{name: "Unknown role", role: "intern", method: http.MethodGet, path: "/stats", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req = req.WithContext(WithUser(req.Context(), storage.User{ID: 1, Name: "u", Role: tc.role}))
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.wantStatus, rr.Code)
+		})
+	}
+}
+
+func TestRequireRoleResponse(t *testing.T) {
+	h := RequireRole(storage.RoleEditor)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req = req.WithContext(WithUser(req.Context(), storage.User{ID: 1, Name: "intern", Role: storage.RoleViewer}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.JSONEq(t, `{
+		"status": "Error",
+		"error": "role editor required",
+		"code": "FORBIDDEN",
+		"required_role": "editor",
+		"role": "viewer"
+	}`, rr.Body.String())
+}
@@ -0,0 +1,38 @@
+// Package foldalias applies the alias policy to the {alias} URL parameter,
+// so links are found by the same alias they were saved under.
+package foldalias
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// param is the name of the URL parameter with the alias
+const param = "alias"
+
+// Normalizer folds an alias the way it is folded before saving
+type Normalizer interface {
+	Normalize(alias string) string
+}
+
+// New rewrites the {alias} URL parameter with the normalized alias.
+// URL parameters are known only after routing, so the middleware must
+// wrap the route itself, e.g. r.With(foldalias.New(policy)).Get("/{alias}", h).
+func New(policy Normalizer) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				for i, key := range rctx.URLParams.Keys {
+					if key == param {
+						rctx.URLParams.Values[i] = policy.Normalize(rctx.URLParams.Values[i])
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,43 @@
+package foldalias
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/lib/alias"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		name      string
+		foldCase  bool
+		path      string
+		wantAlias string
+	}{
+		{name: "Folded", foldCase: true, path: "/url/MyLink", wantAlias: "mylink"},
+		{name: "Folding disabled", foldCase: false, path: "/url/MyLink", wantAlias: "MyLink"},
+		{name: "Nested route", foldCase: true, path: "/url/MyLink/stats", wantAlias: "mylink"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				got = chi.URLParam(r, "alias")
+			}
+
+			fold := New(alias.NewPolicy(alias.Rules{FoldCase: tc.foldCase}))
+			r := chi.NewRouter()
+			r.With(fold).Get("/url/{alias}", handler)
+			r.With(fold).Get("/url/{alias}/stats", handler)
+
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.wantAlias, got)
+		})
+	}
+}
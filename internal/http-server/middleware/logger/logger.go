@@ -1,14 +1,25 @@
 package logger
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func New(log *slog.Logger) func(next http.Handler) http.Handler {
+// RequestObserver receives every completed request, e.g. to export metrics.
+// route is the matched chi route pattern, empty when nothing matched.
+type RequestObserver interface {
+	ObserveRequest(route, method string, status int, d time.Duration)
+}
+
+// observer may be nil.
+func New(log *slog.Logger, observer RequestObserver) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		log = log.With(
 			slog.String("component", "middleware/logger"),
@@ -24,20 +35,83 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 				slog.String("user_agent", r.UserAgent()),
 				slog.String("request_id", middleware.GetReqID(r.Context())),
 			)
+			// span is started by the tracing middleware, if it is enabled
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				entry = entry.With(
+					slog.String("trace_id", sc.TraceID().String()),
+					slog.String("span_id", sc.SpanID().String()),
+				)
+			}
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
+			extra := &attrs{}
+
 			t1 := time.Now()
 			defer func() {
-				entry.Info("request completed",
+				duration := time.Since(t1)
+
+				entry.LogAttrs(r.Context(), slog.LevelInfo, "request completed", append([]slog.Attr{
 					slog.Int("status", ww.Status()),
 					slog.Int("bytes", ww.BytesWritten()),
-					slog.String("duration", time.Since(t1).String()),
-				)
+					slog.String("duration", duration.String()),
+				}, extra.get()...)...)
+
+				if observer != nil {
+					observer.ObserveRequest(routePattern(r), r.Method, status(ww), duration)
+				}
 			}()
 
-			next.ServeHTTP(ww, r)
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), attrsKey{}, extra)))
 		}
 
 		return http.HandlerFunc(fn)
 	}
 }
+
+// routePattern is known only after the router has matched the request
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+
+	return rctx.RoutePattern()
+}
+
+// status of a handler that wrote nothing is 200, as net/http sends it
+func status(ww middleware.WrapResponseWriter) int {
+	if ww.Status() == 0 {
+		return http.StatusOK
+	}
+
+	return ww.Status()
+}
+
+type attrsKey struct{}
+
+// attrs are added to the "request completed" line by inner handlers
+type attrs struct {
+	mu   sync.Mutex
+	list []slog.Attr
+}
+
+func (a *attrs) get() []slog.Attr {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.list
+}
+
+// AddAttrs adds attributes to the line logged when the request completes,
+// e.g. who made the request once it is known. Does nothing outside the middleware.
+func AddAttrs(ctx context.Context, list ...slog.Attr) {
+	a, ok := ctx.Value(attrsKey{}).(*attrs)
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.list = append(a.list, list...)
+}
@@ -0,0 +1,218 @@
+// Package ratelimit limits how often one client may call a route.
+// Every client gets a token bucket: a request takes a token, tokens are
+// refilled at a constant rate up to the burst size. Handlers may Charge
+// more tokens for requests that do more work, e.g. save many links.
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// Policy is Requests per Per on average with bursts of up to Burst requests
+type Policy struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// Enabled reports whether the policy limits anything
+func (p Policy) Enabled() bool {
+	return p.Requests > 0 && p.Per > 0
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps a bucket per client
+type Limiter struct {
+	name    string
+	rate    float64 // tokens per second
+	burst   float64
+	limit   int
+	buckets map[string]*bucket
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+
+	now func() time.Time
+}
+
+// New creates a limiter for the policy, name tells limiters apart in logs.
+// Burst below one means no bursts above the average rate.
+func New(name string, policy Policy) *Limiter {
+	burst := policy.Burst
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		name:    name,
+		rate:    float64(policy.Requests) / policy.Per.Seconds(),
+		burst:   float64(burst),
+		limit:   burst,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// result of taking a token
+type result struct {
+	allowed bool
+	// remaining tokens after the request
+	remaining int
+	// retryAfter is how long until a token is available, zero when allowed
+	retryAfter time.Duration
+	// reset is how long until the bucket is full again
+	reset time.Duration
+}
+
+func (l *Limiter) take(key string) result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	b := l.refill(key, now)
+
+	res := result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.allowed = true
+	} else {
+		res.retryAfter = l.duration(1 - b.tokens)
+	}
+	res.remaining = int(math.Max(0, b.tokens))
+	res.reset = l.duration(l.burst - b.tokens)
+
+	return res
+}
+
+// charge takes n more tokens after the request was allowed. The bucket may
+// go below zero, then the client waits until the debt is refilled.
+func (l *Limiter) charge(key string, n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key, l.now())
+	b.tokens -= float64(n)
+}
+
+// refill returns the bucket of the client with the tokens earned since the last request
+func (l *Limiter) refill(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	return b
+}
+
+// cleanup drops buckets that have been refilled, a new bucket is full
+// anyway. Runs at most once a minute.
+func (l *Limiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < time.Minute {
+		return
+	}
+	l.lastCleanup = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// duration is the time needed to refill the tokens
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// Handler limits requests per api key for authenticated requests and
+// per remote IP for the others. Rejected requests get 429.
+func (l *Limiter) Handler(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log = log.With(
+			slog.String("component", "middleware/ratelimit"),
+			slog.String("limiter", l.name),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := clientKey(r)
+			res := l.take(key)
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(l.limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(res.remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(seconds(res.reset)))
+
+			if !res.allowed {
+				log.Info("rate limit exceeded",
+					slog.String("client", key),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				h.Set("Retry-After", strconv.Itoa(seconds(res.retryAfter)))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error(resp.CodeRateLimited, "rate limit exceeded"))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), chargeCtx{}, func(n int) { l.charge(key, n) })
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+type chargeCtx struct{}
+
+// Charge takes n more tokens from the limiter that allowed the request.
+// It does nothing when n is not positive or the route has no limiter.
+func Charge(ctx context.Context, n int) {
+	if n <= 0 {
+		return
+	}
+	if charge, ok := ctx.Value(chargeCtx{}).(func(int)); ok {
+		charge(n)
+	}
+}
+
+// clientKey is the api key id when the request is authenticated, the remote IP otherwise
+func clientKey(r *http.Request) string {
+	if key, ok := auth.KeyFromContext(r.Context()); ok {
+		return "key:" + strconv.FormatInt(key.ID, 10)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// seconds rounds up, so clients retrying after it are not rejected again
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
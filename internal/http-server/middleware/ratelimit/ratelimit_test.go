@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(policy Policy) (*Limiter, *time.Time) {
+	l := New("test", policy)
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	return l, &now
+}
+
+func TestTokenBucket(t *testing.T) {
+	// 1 request per second, up to 3 at once
+	l, now := newTestLimiter(Policy{Requests: 60, Per: time.Minute, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.take("a").allowed, "request %d", i)
+	}
+
+	res := l.take("a")
+	assert.False(t, res.allowed)
+	assert.Equal(t, time.Second, res.retryAfter)
+
+	// other clients have their own buckets
+	assert.True(t, l.take("b").allowed)
+
+	*now = now.Add(time.Second)
+	assert.True(t, l.take("a").allowed)
+	assert.False(t, l.take("a").allowed)
+
+	// the bucket does not grow above the burst
+	*now = now.Add(time.Hour)
+	res = l.take("a")
+	assert.True(t, res.allowed)
+	assert.Equal(t, 2, res.remaining)
+}
+
+func TestCleanup(t *testing.T) {
+	l, now := newTestLimiter(Policy{Requests: 1, Per: time.Second, Burst: 1})
+
+	l.take("a")
+	l.take("b")
+	assert.Len(t, l.buckets, 2)
+
+	*now = now.Add(2 * time.Minute)
+	l.take("c")
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestHandler(t *testing.T) {
+	l, _ := newTestLimiter(Policy{Requests: 1, Per: 10 * time.Second, Burst: 2})
+	h := l.Handler(slogdiscard.NewDiscardLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(remoteAddr string, key *storage.APIKey) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/url", nil)
+		req.RemoteAddr = remoteAddr
+		if key != nil {
+			req = req.WithContext(auth.WithKey(req.Context(), *key))
+		}
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do("10.0.0.1:1234", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "10", rr.Header().Get("X-RateLimit-Reset"))
+
+	// same IP, another port
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:5678", nil).Code)
+
+	rr = do("10.0.0.1:1234", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "10", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+	assert.JSONEq(t, `{"status":"Error","error":"rate limit exceeded","code":"RATE_LIMITED"}`, rr.Body.String())
+
+	// authenticated requests are limited per key, not per IP
+	key := &storage.APIKey{ID: 7}
+	assert.Equal(t, http.StatusOK, do("10.0.0.1:1234", key).Code)
+	assert.Equal(t, http.StatusOK, do("10.0.0.2:1234", key).Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("10.0.0.3:1234", key).Code)
+}
+
+func TestCharge(t *testing.T) {
+	// 1 request per second, up to 3 at once
+	l, now := newTestLimiter(Policy{Requests: 60, Per: time.Minute, Burst: 3})
+
+	// обработчик сохраняет 5 ссылок, один токен взят при входе
+	h := l.Handler(slogdiscard.NewDiscardLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Charge(r.Context(), 4)
+	}))
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/url/batch", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// запрос больше burst пропускается, но оставляет долг
+	assert.Equal(t, http.StatusOK, do().Code)
+
+	rr := do()
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "3", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+
+	*now = now.Add(3 * time.Second)
+	assert.Equal(t, http.StatusOK, do().Code)
+
+	// без лимитера ничего не происходит
+	Charge(httptest.NewRequest(http.MethodPost, "/", nil).Context(), 10)
+}
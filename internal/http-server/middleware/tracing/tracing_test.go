@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newRouter(recorder *tracetest.SpanRecorder, handler http.HandlerFunc) *chi.Mux {
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	r := chi.NewRouter()
+	r.Use(New(tracer, propagation.TraceContext{}))
+	r.Get("/{alias}", handler)
+
+	return r
+}
+
+func TestContinuesIncomingTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+
+	var inner trace.SpanContext
+	r := newRouter(recorder, func(w http.ResponseWriter, r *http.Request) {
+		inner = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/google", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+
+	assert.Equal(t, "GET /{alias}", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.True(t, span.Parent().IsRemote())
+	assert.Equal(t, span.SpanContext(), inner)
+	assert.Contains(t, span.Attributes(), attribute.String("http.route", "/{alias}"))
+	assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusFound))
+}
+
+func TestServerErrorMarksSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+
+	r := newRouter(recorder, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/google", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
@@ -0,0 +1,140 @@
+package alias
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// Generator makes one alias
+type Generator interface {
+	Generate() (string, error)
+}
+
+// AdaptiveStats are counters since start
+type AdaptiveStats struct {
+	// Length is the current alias length
+	Length int
+	// Collisions is how many generated aliases were already taken
+	Collisions int64
+	// Saturations is how many times a length was found saturated
+	Saturations int64
+}
+
+const (
+	// maxStreak collisions in a row mean the length is saturated, so a
+	// single request grows the length before it runs out of retries
+	maxStreak = 3
+	// window is how many saves the collision rate is counted over
+	window = 100
+	// minSample saves are needed before the rate is trusted
+	minSample = 20
+	// maxRate of collisions means the length is saturated
+	maxRate = 0.2
+)
+
+// Adaptive makes aliases with a generator of the current length and grows
+// the length, up to max, when collisions show that the length is saturated.
+// The caller reports whether each generated alias could be saved with Observe.
+type Adaptive struct {
+	log    *slog.Logger
+	newGen func(length int) Generator
+	max    int
+
+	mu         sync.Mutex
+	length     int
+	gen        Generator
+	saves      int
+	collisions int
+	streak     int
+	atMax      bool
+
+	totalCollisions atomic.Int64
+	saturations     atomic.Int64
+}
+
+// NewAdaptive returns a generator that starts with newGen(length).
+// For words generators the length is the number of words.
+func NewAdaptive(log *slog.Logger, length, max int, newGen func(length int) Generator) *Adaptive {
+	return &Adaptive{
+		log:    log,
+		newGen: newGen,
+		max:    max,
+		length: length,
+		gen:    newGen(length),
+	}
+}
+
+func (a *Adaptive) Generate() (string, error) {
+	a.mu.Lock()
+	gen := a.gen
+	a.mu.Unlock()
+
+	return gen.Generate()
+}
+
+// Observe records whether a generated alias was already taken
+func (a *Adaptive) Observe(collided bool) {
+	if collided {
+		a.totalCollisions.Add(1)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.saves++
+	if collided {
+		a.collisions++
+		a.streak++
+	} else {
+		a.streak = 0
+	}
+
+	rate := float64(a.collisions) / float64(a.saves)
+	if a.streak >= maxStreak || (a.saves >= minSample && rate >= maxRate) {
+		a.saturated(rate)
+		return
+	}
+
+	if a.saves >= window {
+		a.saves, a.collisions = 0, 0
+	}
+}
+
+// saturated grows the length, a.mu must be held
+func (a *Adaptive) saturated(rate float64) {
+	a.saturations.Add(1)
+	a.saves, a.collisions, a.streak = 0, 0, 0
+
+	if a.length >= a.max {
+		// предупреждаем один раз, дальше только растёт счётчик
+		if !a.atMax {
+			a.atMax = true
+			a.log.Warn("alias length saturated, max length reached",
+				slog.Int("length", a.length),
+				slog.Float64("collision_rate", rate),
+			)
+		}
+		return
+	}
+
+	a.length++
+	a.gen = a.newGen(a.length)
+
+	a.log.Warn("alias length saturated, growing",
+		slog.Int("length", a.length),
+		slog.Float64("collision_rate", rate),
+	)
+}
+
+func (a *Adaptive) Stats() AdaptiveStats {
+	a.mu.Lock()
+	length := a.length
+	a.mu.Unlock()
+
+	return AdaptiveStats{
+		Length:      length,
+		Collisions:  a.totalCollisions.Load(),
+		Saturations: a.saturations.Load(),
+	}
+}
@@ -0,0 +1,59 @@
+package alias
+
+import (
+	"testing"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptive(t *testing.T) {
+	newAdaptive := func() *Adaptive {
+		return NewAdaptive(slogdiscard.NewDiscardLogger(), 2, 4, func(length int) Generator { return NewRandom(length) })
+	}
+
+	t.Run("Grows on a collision streak", func(t *testing.T) {
+		a := newAdaptive()
+		for i := 0; i < maxStreak; i++ {
+			a.Observe(true)
+		}
+
+		s, err := a.Generate()
+		require.NoError(t, err)
+		assert.Len(t, s, 3)
+		assert.Equal(t, AdaptiveStats{Length: 3, Collisions: maxStreak, Saturations: 1}, a.Stats())
+	})
+
+	t.Run("Grows on a high collision rate", func(t *testing.T) {
+		a := newAdaptive()
+		for i := 0; i < minSample; i++ {
+			// каждая третья попытка — коллизия, серий нет
+			a.Observe(i%3 == 0)
+		}
+
+		assert.Equal(t, 3, a.Stats().Length)
+	})
+
+	t.Run("Keeps length on rare collisions", func(t *testing.T) {
+		a := newAdaptive()
+		for i := 0; i < 10*window; i++ {
+			a.Observe(i%10 == 0)
+		}
+
+		assert.Equal(t, 2, a.Stats().Length)
+		assert.Equal(t, int64(0), a.Stats().Saturations)
+	})
+
+	t.Run("Stops at max length", func(t *testing.T) {
+		a := newAdaptive()
+		for i := 0; i < 5*maxStreak; i++ {
+			a.Observe(true)
+		}
+
+		stats := a.Stats()
+		assert.Equal(t, 4, stats.Length)
+		assert.Equal(t, int64(5), stats.Saturations)
+	})
+}
@@ -0,0 +1,70 @@
+// Package alias generates aliases for links saved without one.
+package alias
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// base62 is the alphabet of id based aliases, in order of digit value
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// maxLength is the longest id based alias: 62^maxLength still fits in uint64
+const maxLength = 10
+
+// ErrOutOfIDs is returned when an id does not fit in maxLength chars
+var ErrOutOfIDs = errors.New("id is too big for an alias")
+
+// Counter hands out ids to id based generators
+type Counter interface {
+	Next() (uint64, error)
+}
+
+// AtomicCounter is an in-process Counter. It does not coordinate with other
+// instances, so they may hand out the same ids; the caller retries on
+// collision and gets the next id.
+type AtomicCounter struct {
+	n atomic.Uint64
+}
+
+// NewCounter returns a counter whose first id is last+1
+func NewCounter(last uint64) *AtomicCounter {
+	c := &AtomicCounter{}
+	c.n.Store(last)
+	return c
+}
+
+func (c *AtomicCounter) Next() (uint64, error) {
+	return c.n.Add(1), nil
+}
+
+// pow62 returns 62^n
+func pow62(n int) uint64 {
+	p := uint64(1)
+	for i := 0; i < n; i++ {
+		p *= 62
+	}
+	return p
+}
+
+// lengthFor returns how many base62 digits n takes, at least minLength
+func lengthFor(n uint64, minLength int) (int, error) {
+	length := max(minLength, 1)
+	for length <= maxLength && n >= pow62(length) {
+		length++
+	}
+	if length > maxLength {
+		return 0, ErrOutOfIDs
+	}
+	return length, nil
+}
+
+// encode writes n with the given alphabet, left padded to length with its zero digit
+func encode(n uint64, alphabet string, length int) string {
+	b := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		b[i] = alphabet[n%62]
+		n /= 62
+	}
+	return string(b)
+}
@@ -0,0 +1,100 @@
+package alias
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decode is the reverse of encode
+func decode(s, alphabet string) uint64 {
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		n = n*62 + uint64(strings.IndexByte(alphabet, s[i]))
+	}
+	return n
+}
+
+func TestGenerators(t *testing.T) {
+	cases := []struct {
+		name    string
+		gen     Generator
+		pattern string
+	}{
+		{name: "Random", gen: NewRandom(6), pattern: `^[0-9A-Za-z]{6}$`},
+		{name: "Sequential", gen: NewSequential(NewCounter(0), 6), pattern: `^[0-9A-Za-z]{6}$`},
+		{name: "Hashids", gen: NewHashids(NewCounter(0), "salt", 6), pattern: `^[0-9A-Za-z]{6}$`},
+		{name: "Words", gen: NewWords(2), pattern: `^([bdfgklmnprstvz][aeiou]){3}-([bdfgklmnprstvz][aeiou]){3}$`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			re := regexp.MustCompile(tc.pattern)
+			seen := make(map[string]struct{})
+			for i := 0; i < 5000; i++ {
+				s, err := tc.gen.Generate()
+				require.NoError(t, err)
+				require.Regexp(t, re, s)
+				_, dup := seen[s]
+				require.False(t, dup, "duplicate alias %q", s)
+				seen[s] = struct{}{}
+			}
+		})
+	}
+}
+
+func TestSequential(t *testing.T) {
+	g := NewSequential(NewCounter(59), 1)
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		s, err := g.Generate()
+		require.NoError(t, err)
+		got = append(got, s)
+	}
+
+	assert.Equal(t, []string{"y", "z", "10"}, got)
+}
+
+func TestHashidsIsBijective(t *testing.T) {
+	g := NewHashids(NewCounter(0), "salt", 2)
+
+	// все двухсимвольные алиасы, каждый ровно один раз
+	seen := make(map[string]struct{}, 62*62)
+	for id := uint64(0); id < 62*62; id++ {
+		s, err := g.encode(id)
+		require.NoError(t, err)
+		require.Len(t, s, 2)
+		seen[s] = struct{}{}
+	}
+	assert.Len(t, seen, 62*62)
+
+	// дальше алиасы становятся длиннее
+	s, err := g.encode(62 * 62)
+	require.NoError(t, err)
+	assert.Len(t, s, 3)
+}
+
+func TestHashidsHidesOrder(t *testing.T) {
+	g := NewHashids(NewCounter(0), "salt", 6)
+
+	a, err := g.encode(1)
+	require.NoError(t, err)
+	b, err := g.encode(2)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, decode(a, g.alphabet)+1, decode(b, g.alphabet))
+	assert.NotEqual(t, a[:5], b[:5])
+
+	other, err := NewHashids(NewCounter(0), "pepper", 6).encode(1)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, other)
+}
+
+func TestOutOfIDs(t *testing.T) {
+	_, err := NewSequential(NewCounter(pow62(maxLength)-1), 1).Generate()
+	assert.ErrorIs(t, err, ErrOutOfIDs)
+}
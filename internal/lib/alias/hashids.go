@@ -0,0 +1,85 @@
+package alias
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// Hashids encodes ids from a counter like Sqids/Hashids do: aliases are as
+// short as sequential ones, but neighbouring ids give unrelated aliases and
+// the id can not be read back without the salt.
+//
+// Within each alias length n the id is mapped by x*mult+add mod 62^n, which
+// is a bijection because mult is coprime with 62, and then written with an
+// alphabet shuffled by the salt. So two ids never get the same alias.
+type Hashids struct {
+	counter   Counter
+	minLength int
+	alphabet  string
+	mult      uint64
+	add       uint64
+}
+
+// NewHashids returns a generator keyed by salt. Changing the salt changes
+// the aliases of future ids, which may then collide with existing aliases
+// and be retried.
+func NewHashids(counter Counter, salt string, minLength int) *Hashids {
+	sum := sha256.Sum256([]byte(salt))
+
+	mult := binary.BigEndian.Uint64(sum[0:8]) | 1
+	for mult%31 == 0 {
+		mult += 2
+	}
+
+	return &Hashids{
+		counter:   counter,
+		minLength: minLength,
+		alphabet:  shuffle(base62, salt),
+		mult:      mult,
+		add:       binary.BigEndian.Uint64(sum[8:16]),
+	}
+}
+
+func (g *Hashids) Generate() (string, error) {
+	const op = "lib.alias.Hashids.Generate"
+
+	id, err := g.counter.Next()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	s, err := g.encode(id)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+func (g *Hashids) encode(id uint64) (string, error) {
+	length, err := lengthFor(id, g.minLength)
+	if err != nil {
+		return "", err
+	}
+
+	m := pow62(length)
+	// id < m, so the high half of the product is less than m as Div64 requires
+	hi, lo := bits.Mul64(id, g.mult)
+	_, x := bits.Div64(hi, lo, m)
+	x = (x + g.add%m) % m
+
+	return encode(x, g.alphabet, length), nil
+}
+
+// shuffle permutes the alphabet with a Fisher-Yates shuffle driven by the salt
+func shuffle(alphabet, salt string) string {
+	b := []byte(alphabet)
+	for i := len(b) - 1; i > 0; i-- {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", salt, i)))
+		j := int(binary.BigEndian.Uint64(sum[:8]) % uint64(i+1))
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
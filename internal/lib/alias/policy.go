@@ -0,0 +1,157 @@
+package alias
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// rules reported by Policy.Check
+const (
+	RuleMin      = "min"
+	RuleMax      = "max"
+	RuleCharset  = "charset"
+	RuleReserved = "reserved"
+	RuleBlocked  = "blocked"
+)
+
+// Rules configure a Policy
+type Rules struct {
+	MinLength int
+	MaxLength int
+	// Charset lists every char an alias may contain
+	Charset string
+	// FoldCase lowercases aliases given by users before they are checked and saved,
+	// and aliases of lookups, see middleware/foldalias
+	FoldCase bool
+	// Reserved aliases may not be taken, e.g. first segments of routes
+	Reserved []string
+	// Blocked words may not appear anywhere in an alias
+	Blocked []string
+}
+
+// Policy decides which aliases users may choose. Reserved and blocked
+// words are compared case-insensitively.
+type Policy struct {
+	minLength int
+	maxLength int
+	charset   string
+	foldCase  bool
+	reserved  map[string]struct{}
+	blocked   []string
+}
+
+func NewPolicy(r Rules) *Policy {
+	p := &Policy{
+		minLength: r.MinLength,
+		maxLength: r.MaxLength,
+		charset:   r.Charset,
+		foldCase:  r.FoldCase,
+		reserved:  make(map[string]struct{}, len(r.Reserved)),
+	}
+	p.Reserve(r.Reserved...)
+	for _, w := range r.Blocked {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			p.blocked = append(p.blocked, w)
+		}
+	}
+
+	return p
+}
+
+// Reserve adds reserved aliases. It is not safe to call while Check runs,
+// so routes are reserved before the server starts.
+func (p *Policy) Reserve(words ...string) {
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			p.reserved[w] = struct{}{}
+		}
+	}
+}
+
+// Normalize applies case folding to an alias given by a user
+func (p *Policy) Normalize(alias string) string {
+	if p.foldCase {
+		return strings.ToLower(alias)
+	}
+	return alias
+}
+
+// Check returns the rule the alias breaks and its parameter,
+// or an empty rule if the alias is allowed
+func (p *Policy) Check(alias string) (rule, param string) {
+	n := len([]rune(alias))
+	if n < p.minLength {
+		return RuleMin, strconv.Itoa(p.minLength)
+	}
+	if p.maxLength > 0 && n > p.maxLength {
+		return RuleMax, strconv.Itoa(p.maxLength)
+	}
+
+	if p.charset != "" {
+		for _, c := range alias {
+			if !strings.ContainsRune(p.charset, c) {
+				return RuleCharset, string(c)
+			}
+		}
+	}
+
+	lower := strings.ToLower(alias)
+	if _, ok := p.reserved[lower]; ok {
+		return RuleReserved, ""
+	}
+	for _, w := range p.blocked {
+		if strings.Contains(lower, w) {
+			return RuleBlocked, ""
+		}
+	}
+
+	return "", ""
+}
+
+// Allowed reports whether the alias may be used at all, length and
+// charset aside. Generated aliases that are not allowed are regenerated.
+func (p *Policy) Allowed(alias string) bool {
+	switch rule, _ := p.Check(alias); rule {
+	case RuleReserved, RuleBlocked:
+		return false
+	}
+	return true
+}
+
+// LoadBlocklist reads blocked words from a file, one per line.
+// Empty lines and lines starting with # are skipped.
+func LoadBlocklist(path string) ([]string, error) {
+	const op = "lib.alias.LoadBlocklist"
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	words, err := readBlocklist(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return words, nil
+}
+
+func readBlocklist(r io.Reader) ([]string, error) {
+	var words []string
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+
+	return words, sc.Err()
+}
@@ -0,0 +1,59 @@
+package alias
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	p := NewPolicy(Rules{
+		MinLength: 3,
+		MaxLength: 10,
+		Charset:   "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_",
+		Reserved:  []string{"url", "Admin"},
+		Blocked:   []string{"darn"},
+	})
+	p.Reserve("health")
+
+	cases := []struct {
+		alias     string
+		wantRule  string
+		wantParam string
+	}{
+		{alias: "my-link_1"},
+		{alias: "ab", wantRule: RuleMin, wantParam: "3"},
+		{alias: "abcdefghijk", wantRule: RuleMax, wantParam: "10"},
+		{alias: "a/b/c", wantRule: RuleCharset, wantParam: "/"},
+		{alias: "my link", wantRule: RuleCharset, wantParam: " "},
+		{alias: "url", wantRule: RuleReserved},
+		{alias: "ADMIN", wantRule: RuleReserved},
+		{alias: "health", wantRule: RuleReserved},
+		{alias: "urls"},
+		{alias: "xDarnx", wantRule: RuleBlocked},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.alias, func(t *testing.T) {
+			rule, param := p.Check(tc.alias)
+			assert.Equal(t, tc.wantRule, rule)
+			assert.Equal(t, tc.wantParam, param)
+		})
+	}
+
+	assert.True(t, p.Allowed("ab"))
+	assert.False(t, p.Allowed("url"))
+}
+
+func TestPolicyNormalize(t *testing.T) {
+	assert.Equal(t, "MyLink", NewPolicy(Rules{}).Normalize("MyLink"))
+	assert.Equal(t, "mylink", NewPolicy(Rules{FoldCase: true}).Normalize("MyLink"))
+}
+
+func TestReadBlocklist(t *testing.T) {
+	words, err := readBlocklist(strings.NewReader("# comment\ndarn\n\n  heck  \n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"darn", "heck"}, words)
+}
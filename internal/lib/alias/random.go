@@ -0,0 +1,27 @@
+package alias
+
+import (
+	"fmt"
+
+	"url-shortener/internal/lib/random"
+)
+
+// Random makes aliases of random base62 chars read from crypto/rand
+type Random struct {
+	length int
+}
+
+func NewRandom(length int) *Random {
+	return &Random{length: length}
+}
+
+func (g *Random) Generate() (string, error) {
+	const op = "lib.alias.Random.Generate"
+
+	s, err := random.NewRandomString(g.length)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
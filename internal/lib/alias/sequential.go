@@ -0,0 +1,30 @@
+package alias
+
+import "fmt"
+
+// Sequential encodes ids from a counter in base62: 000001, 000002 and so on.
+// Aliases are as short as possible, but the number of links is easy to guess.
+type Sequential struct {
+	counter   Counter
+	minLength int
+}
+
+func NewSequential(counter Counter, minLength int) *Sequential {
+	return &Sequential{counter: counter, minLength: minLength}
+}
+
+func (g *Sequential) Generate() (string, error) {
+	const op = "lib.alias.Sequential.Generate"
+
+	id, err := g.counter.Next()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	length, err := lengthFor(id, g.minLength)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return encode(id, base62, length), nil
+}
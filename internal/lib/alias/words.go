@@ -0,0 +1,61 @@
+package alias
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// syllables of pronounceable words, consonant then vowel
+const (
+	consonants = "bdfgklmnprstvz"
+	vowels     = "aeiou"
+)
+
+// syllablesPerWord gives 70^3, about 343 thousand, different words
+const syllablesPerWord = 3
+
+// Words makes pronounceable aliases of random made-up words joined with
+// a dash, e.g. "tavoku-belimo". They are easy to read out and type.
+type Words struct {
+	words int
+}
+
+func NewWords(words int) *Words {
+	return &Words{words: words}
+}
+
+func (g *Words) Generate() (string, error) {
+	const op = "lib.alias.Words.Generate"
+
+	var sb strings.Builder
+	for w := 0; w < g.words; w++ {
+		if w > 0 {
+			sb.WriteByte('-')
+		}
+		for s := 0; s < syllablesPerWord; s++ {
+			c, err := pick(consonants)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", op, err)
+			}
+			v, err := pick(vowels)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", op, err)
+			}
+			sb.WriteByte(c)
+			sb.WriteByte(v)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// pick returns a random char of s
+func pick(s string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(s))))
+	if err != nil {
+		return 0, err
+	}
+	return s[n.Int64()], nil
+}
@@ -25,7 +25,10 @@ func GetRedirect(url string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	if resp.StatusCode != http.StatusFound {
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
 		return "", fmt.Errorf("%s: %w: %d", op, ErrInvalidstatusCode, resp.StatusCode)
 	}
 
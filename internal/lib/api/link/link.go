@@ -0,0 +1,40 @@
+// Package link is the JSON view of a stored link shared by the url handlers.
+package link
+
+import (
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+type Link struct {
+	ID    int64  `json:"id"`
+	Alias string `json:"alias"`
+	URL   string `json:"url"`
+	// RedirectCode is omitted when the server default is used
+	RedirectCode int        `json:"redirect_code,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	// OwnerID is omitted for links saved before owners were recorded
+	OwnerID int64 `json:"owner_id,omitempty"`
+}
+
+func FromURL(u storage.URL) Link {
+	return Link{
+		ID:           u.ID,
+		Alias:        u.Alias,
+		URL:          u.URL,
+		RedirectCode: u.RedirectCode,
+		CreatedAt:    timePtr(u.CreatedAt),
+		ExpiresAt:    timePtr(u.ExpiresAt),
+		OwnerID:      u.OwnerID,
+	}
+}
+
+// timePtr turns zero time into nil so it is left out of JSON
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -2,6 +2,7 @@ package response
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -10,6 +11,21 @@ import (
 type Response struct {
 	Status string `json:"status"` // Error, Ok
 	Error  string `json:"error,omitempty"`
+	// Code is a machine-readable kind of the error, one of the Code* constants
+	Code string `json:"code,omitempty"`
+	// Fields describe what is wrong with each invalid field of the request
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError is one failed validation rule
+type FieldError struct {
+	// Field is the json name of the field
+	Field string `json:"field"`
+	// Rule is the failed rule, e.g. required, url or oneof
+	Rule string `json:"rule"`
+	// Param is the rule parameter, e.g. allowed values for oneof
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
 }
 
 const (
@@ -17,35 +33,109 @@ const (
 	StatusError = "Error"
 )
 
+// error codes, clients may rely on them
+const (
+	// CodeBadRequest: the request could not be read, e.g. broken JSON (400)
+	CodeBadRequest = "BAD_REQUEST"
+	// CodeValidationFailed: some fields are invalid, see Fields (422)
+	CodeValidationFailed = "VALIDATION_FAILED"
+	// CodeUnauthorized: no valid api key (401)
+	CodeUnauthorized = "UNAUTHORIZED"
+	// CodeForbidden: the client is known but may not do the request (403)
+	CodeForbidden = "FORBIDDEN"
+	// CodeNotFound: no such link (404)
+	CodeNotFound = "NOT_FOUND"
+	// CodeAliasExists: the alias is taken (409)
+	CodeAliasExists = "ALIAS_EXISTS"
+	// CodeGone: the link has expired (410)
+	CodeGone = "GONE"
+	// CodeRateLimited: too many requests (429)
+	CodeRateLimited = "RATE_LIMITED"
+	// CodeInternal: something failed on our side (500)
+	CodeInternal = "INTERNAL"
+	// CodeUnavailable: a dependency such as the storage does not answer (503)
+	CodeUnavailable = "UNAVAILABLE"
+	// CodeNotSaved: a valid item of an all-or-nothing batch that was not
+	// saved because other items failed
+	CodeNotSaved = "NOT_SAVED"
+)
+
 func OK() Response {
 	return Response{
 		Status: StatusOk,
 	}
 }
 
-func Error(msg string) Response {
+func Error(code, msg string) Response {
 	return Response{
 		Status: StatusError,
 		Error:  msg,
+		Code:   code,
 	}
 }
 
+// NewValidator returns a validator that names fields in errors by their json names
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	return v
+}
+
 func ValidationError(errs validator.ValidationErrors) Response {
-	var errMsgs []string
+	fields := make([]FieldError, 0, len(errs))
 
 	for _, err := range errs {
+		fe := FieldError{
+			Field: err.Field(),
+			Rule:  err.ActualTag(),
+			Param: err.Param(),
+		}
+
 		switch err.ActualTag() {
-		case "requered":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
+		case "required":
+			fe.Message = fmt.Sprintf("field %s is a required field", err.Field())
 		case "url":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid URL", err.Field()))
+			fe.Message = fmt.Sprintf("field %s is not a valid URL", err.Field())
+		case "oneof":
+			fe.Message = fmt.Sprintf("field %s must be one of %s", err.Field(), strings.ReplaceAll(err.Param(), " ", ", "))
+		case "min":
+			fe.Message = fmt.Sprintf("field %s must be at least %s characters long", err.Field(), err.Param())
+		case "max":
+			fe.Message = fmt.Sprintf("field %s must be at most %s characters long", err.Field(), err.Param())
+		case "charset":
+			fe.Message = fmt.Sprintf("field %s must not contain %q", err.Field(), err.Param())
+		case "reserved":
+			fe.Message = fmt.Sprintf("field %s is reserved", err.Field())
+		case "blocked":
+			fe.Message = fmt.Sprintf("field %s contains a blocked word", err.Field())
 		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not valid", err.Field()))
+			fe.Message = fmt.Sprintf("field %s is not valid", err.Field())
 		}
+
+		fields = append(fields, fe)
+	}
+
+	return FieldsError(fields...)
+}
+
+// FieldsError reports invalid fields found by checks other than the validator
+func FieldsError(fields ...FieldError) Response {
+	msgs := make([]string, 0, len(fields))
+	for _, f := range fields {
+		msgs = append(msgs, f.Message)
 	}
 
 	return Response{
 		Status: StatusError,
-		Error:  strings.Join(errMsgs, ", "),
+		Error:  strings.Join(msgs, ", "),
+		Code:   CodeValidationFailed,
+		Fields: fields,
 	}
 }
@@ -0,0 +1,94 @@
+// Package apikey generates api keys and hashes them for storage.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// scopes a key may be granted
+const (
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeDelete = "delete"
+)
+
+// Scopes are all known scopes
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeDelete}
+
+const (
+	keyPrefix = "usk_"
+	keyLength = 40
+	// prefixLength is how much of the key is kept in plain text to identify it
+	prefixLength = len(keyPrefix) + 8
+
+	alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// Generate returns a new random key
+func Generate() (string, error) {
+	const op = "lib.apikey.Generate"
+
+	b := make([]byte, keyLength)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+
+	return keyPrefix + string(b), nil
+}
+
+// Hash is what is stored instead of the key. Keys are long and random,
+// so a plain SHA-256 is enough and lets keys be looked up by hash.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Prefix returns the part of the key shown in listings
+func Prefix(key string) string {
+	if len(key) < prefixLength {
+		return key
+	}
+	return key[:prefixLength]
+}
+
+// ParseScopes parses a comma separated list of scopes
+func ParseScopes(s string) ([]string, error) {
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, scope := range strings.Split(s, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if !known(scope) {
+			return nil, fmt.Errorf("unknown scope %q, expected %s", scope, strings.Join(Scopes, ", "))
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+
+	return scopes, nil
+}
+
+func known(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
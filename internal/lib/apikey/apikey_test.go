@@ -0,0 +1,52 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	a, err := Generate()
+	require.NoError(t, err)
+	b, err := Generate()
+	require.NoError(t, err)
+
+	assert.NotEqual(t, a, b)
+	assert.True(t, strings.HasPrefix(a, "usk_"))
+	assert.Len(t, a, len("usk_")+40)
+	assert.Equal(t, a[:12], Prefix(a))
+
+	assert.Equal(t, Hash(a), Hash(a))
+	assert.NotEqual(t, Hash(a), Hash(b))
+	assert.NotContains(t, Hash(a), a)
+}
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{name: "One", in: "read", want: []string{"read"}},
+		{name: "Several", in: "read, write,delete", want: []string{"read", "write", "delete"}},
+		{name: "Duplicates", in: "read,read", want: []string{"read"}},
+		{name: "Unknown", in: "read,admin", wantErr: true},
+		{name: "Empty", in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScopes(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Package buildinfo describes the running binary. Version, Commit and Time
+// are set at build time:
+//
+//	go build -ldflags "-X url-shortener/internal/lib/buildinfo.Version=v1.2.0 \
+//		-X url-shortener/internal/lib/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//		-X url-shortener/internal/lib/buildinfo.Time=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/url-shortener
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Time    = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Time      string `json:"time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info. Without ldflags the commit and time are taken
+// from the VCS stamp that go build adds inside a git checkout.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Time:      Time,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.Time == "":
+				info.Time = s.Value
+			}
+		}
+	}
+
+	return info
+}
@@ -65,7 +65,7 @@ func (h *Prettyhandler) Handle(_ context.Context, r slog.Record) error {
 	if len(fields) > 0 {
 		b, err = json.MarshalIndent(fields, "", "  ")
 		if err != nil {
-			fmt.Errorf("%s: %w", b, err)
+			return fmt.Errorf("marshal log fields: %w", err)
 		}
 	}
 
@@ -1,22 +1,39 @@
 package random
 
 import (
-	"math/rand"
-	"time"
+	"crypto/rand"
+	"fmt"
 )
 
-// NewRandomString generates random string with given size
-func NewRandomString(size int) string {
-	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+const chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
+	"abcdefghijklmnopqrstuvwxyz" +
+	"0123456789"
 
-	chars := []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
-		"abcdefghijklmnopqrstuvwxyz" +
-		"0123456789")
+// maxByte is the largest multiple of len(chars) that fits in a byte,
+// bytes above it are dropped so that every char is equally likely
+const maxByte = 256 - 256%len(chars)
 
-	b := make([]rune, size)
-	for i := range b {
-		b[i] = chars[rnd.Intn(len(chars))]
+// NewRandomString generates random string with given size.
+// It reads crypto/rand, so the result is safe to use where it must not be guessed.
+func NewRandomString(size int) (string, error) {
+	const op = "lib.random.NewRandomString"
+
+	b := make([]byte, 0, size)
+	buf := make([]byte, size+size/4+1)
+	for len(b) < size {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		for _, c := range buf {
+			if int(c) >= maxByte {
+				continue
+			}
+			b = append(b, chars[int(c)%len(chars)])
+			if len(b) == size {
+				break
+			}
+		}
 	}
 
-	return string(b)
+	return string(b), nil
 }
@@ -22,7 +22,10 @@ func TestNewRandom(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewRandomString(tt.size)
+			s, err := NewRandomString(tt.size)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
 			if len(s) != tt.size {
 				t.Fatalf("unexpected length: got %d, want %d", len(s), tt.size)
 			}
@@ -35,3 +38,17 @@ func TestNewRandom(t *testing.T) {
 		})
 	}
 }
+
+func TestNewRandomUnique(t *testing.T) {
+	seen := make(map[string]struct{}, 10000)
+	for i := 0; i < 10000; i++ {
+		s, err := NewRandomString(8)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := seen[s]; ok {
+			t.Fatalf("duplicate string %q after %d calls", s, i)
+		}
+		seen[s] = struct{}{}
+	}
+}
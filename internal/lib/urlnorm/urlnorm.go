@@ -0,0 +1,64 @@
+// Package urlnorm brings equivalent urls to one form, so that the same
+// target submitted twice can be recognised.
+package urlnorm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// defaultPorts are dropped from the host
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalize lowercases the scheme and host, drops the default port,
+// sorts query parameters by name and turns an empty path into "/".
+// Values of a repeated parameter keep their order.
+func Normalize(raw string) (string, error) {
+	const op = "lib.urlnorm.Normalize"
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 без порта остаётся в скобках
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+
+	if u.RawQuery != "" {
+		// Encode сортирует параметры по имени
+		u.RawQuery = u.Query().Encode()
+	}
+
+	return u.String(), nil
+}
+
+// Hash returns the hex SHA-256 of the normalized url. A url that can't be
+// parsed is hashed as is.
+func Hash(raw string) string {
+	normalized, err := Normalize(raw)
+	if err != nil {
+		normalized = raw
+	}
+
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,40 @@
+package urlnorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "Already normal", raw: "https://example.com/a?x=1", want: "https://example.com/a?x=1"},
+		{name: "Host case", raw: "HTTPS://Example.COM/Path", want: "https://example.com/Path"},
+		{name: "Default http port", raw: "http://example.com:80/", want: "http://example.com/"},
+		{name: "Default https port", raw: "https://example.com:443/", want: "https://example.com/"},
+		{name: "Other port", raw: "https://example.com:8443/", want: "https://example.com:8443/"},
+		{name: "Empty path", raw: "https://example.com", want: "https://example.com/"},
+		{name: "Query order", raw: "https://example.com/?b=2&a=1&b=1", want: "https://example.com/?a=1&b=2&b=1"},
+		{name: "IPv6", raw: "http://[::1]:80/", want: "http://[::1]/"},
+		{name: "Fragment kept", raw: "https://example.com/#top", want: "https://example.com/#top"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Normalize(tc.raw)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, Hash("https://example.com/?a=1&b=2"), Hash("HTTPS://EXAMPLE.com:443?b=2&a=1"))
+	assert.NotEqual(t, Hash("https://example.com/a"), Hash("https://example.com/b"))
+	assert.Len(t, Hash("::not a url"), 64)
+}
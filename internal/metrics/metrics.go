@@ -0,0 +1,160 @@
+// Package metrics keeps the Prometheus collectors of the service and
+// serves them on /metrics.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/cache"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "url_shortener"
+
+// redirect results
+const (
+	RedirectHit     = "hit"
+	RedirectMiss    = "miss"
+	RedirectExpired = "expired"
+)
+
+// Metrics is what the service reports. Its methods match the small
+// interfaces declared by the middleware, handlers and storage decorator.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	redirects       *prometheus.CounterVec
+	storageDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Short link lookups by result: hit, miss or expired.",
+		}, []string{"result"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_query_duration_seconds",
+			Help:      "Storage operation latency by operation and result.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"op", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.redirects,
+		m.storageDuration,
+	)
+
+	// результаты есть сразу, даже до первого перехода
+	for _, result := range []string{RedirectHit, RedirectMiss, RedirectExpired} {
+		m.redirects.WithLabelValues(result)
+	}
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a completed HTTP request. route is the matched
+// route pattern, so that aliases don't become label values.
+func (m *Metrics) ObserveRequest(route, method string, status int, d time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	code := strconv.Itoa(status)
+
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.requestDuration.WithLabelValues(route, method, code).Observe(d.Seconds())
+}
+
+// CountRedirect records the result of resolving a short link
+func (m *Metrics) CountRedirect(result string) {
+	m.redirects.WithLabelValues(result).Inc()
+}
+
+// ObserveQuery records a storage operation
+func (m *Metrics) ObserveQuery(op string, err error, d time.Duration) {
+	m.storageDuration.WithLabelValues(op, queryResult(err)).Observe(d.Seconds())
+}
+
+func queryResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, storage.ErrURLNotFound), errors.Is(err, storage.ErrAPIKeyNotFound), errors.Is(err, storage.ErrUserNotFound):
+		return "not_found"
+	case errors.Is(err, storage.ErrURLExists):
+		return "exists"
+	default:
+		return "error"
+	}
+}
+
+// WatchAliases reports the alias length and the collisions seen by the generator.
+// Every collision makes save retry with a new alias.
+func (m *Metrics) WatchAliases(a *alias.Adaptive) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "alias_length",
+			Help:      "Current length of generated aliases.",
+		}, func() float64 { return float64(a.Stats().Length) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "alias_collisions_total",
+			Help:      "Generated aliases that were already taken and were retried.",
+		}, func() float64 { return float64(a.Stats().Collisions) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "alias_saturations_total",
+			Help:      "Times the alias length was found saturated.",
+		}, func() float64 { return float64(a.Stats().Saturations) }),
+	)
+}
+
+// WatchCache reports lookups of the alias cache
+func (m *Metrics) WatchCache(c *cache.Storage) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Alias lookups answered by the cache.",
+		}, func() float64 { return float64(c.Stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Alias lookups that went to the storage.",
+		}, func() float64 { return float64(c.Stats().Misses) }),
+	)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	return rr.Body.String()
+}
+
+func TestRequestsUseRoutePattern(t *testing.T) {
+	m := New()
+
+	r := chi.NewRouter()
+	r.Use(logger.New(slogdiscard.NewDiscardLogger(), m))
+	r.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusFound)
+	})
+
+	for _, path := range []string{"/abc", "/def", "/a/b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `url_shortener_http_requests_total{method="GET",route="/{alias}",status="302"} 2`)
+	assert.Contains(t, body, `url_shortener_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `url_shortener_http_request_duration_seconds_count{method="GET",route="/{alias}",status="302"} 2`)
+	assert.NotContains(t, body, "abc")
+}
+
+func TestRedirectsAndQueries(t *testing.T) {
+	m := New()
+
+	m.CountRedirect(RedirectHit)
+	m.CountRedirect(RedirectHit)
+	m.ObserveQuery("GetURL", nil, time.Millisecond)
+	m.ObserveQuery("GetURL", fmt.Errorf("op: %w", storage.ErrURLNotFound), time.Millisecond)
+	m.ObserveQuery("SaveURL", context.DeadlineExceeded, time.Millisecond)
+	m.ObserveQuery("GetAPIKeyByHash", storage.ErrAPIKeyNotFound, time.Millisecond)
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `url_shortener_redirects_total{result="hit"} 2`)
+	assert.Contains(t, body, `url_shortener_redirects_total{result="miss"} 0`)
+	assert.Contains(t, body, `url_shortener_storage_query_duration_seconds_count{op="GetURL",result="ok"} 1`)
+	assert.Contains(t, body, `url_shortener_storage_query_duration_seconds_count{op="GetURL",result="not_found"} 1`)
+	assert.Contains(t, body, `url_shortener_storage_query_duration_seconds_count{op="SaveURL",result="error"} 1`)
+	assert.Contains(t, body, `url_shortener_storage_query_duration_seconds_count{op="GetAPIKeyByHash",result="not_found"} 1`)
+}
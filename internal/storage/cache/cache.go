@@ -0,0 +1,192 @@
+// Package cache keeps recently resolved aliases in memory in front of
+// another storage.URLStorage.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// Stats are cache lookup counters since start
+type Stats struct {
+	Hits   int64
+	Misses int64
+}
+
+type entry struct {
+	alias     string
+	url       storage.URL
+	notFound  bool
+	expiresAt time.Time
+}
+
+// Storage is a read-through LRU cache for GetURL. Found links are kept
+// for ttl, missing aliases for negativeTTL. SaveURL, UpdateURL and
+// DeleteURL go to the underlying storage and drop the cached entry.
+// SaveURLs drops the saved aliases as well. ListURLs and GetURLByTarget
+// are not cached.
+type Storage struct {
+	next        storage.URLStorage
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is the most recently used
+
+	hits   atomic.Int64
+	misses atomic.Int64
+
+	now func() time.Time
+}
+
+func New(next storage.URLStorage, size int, ttl, negativeTTL time.Duration) *Storage {
+	return &Storage{
+		next:        next,
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     make(map[string]*list.Element, size),
+		lru:         list.New(),
+		now:         time.Now,
+	}
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	if e, ok := s.lookup(alias); ok {
+		s.hits.Add(1)
+		if e.notFound {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+		return e.url, nil
+	}
+
+	s.misses.Add(1)
+
+	u, err := s.next.GetURL(ctx, alias)
+	switch {
+	case err == nil:
+		s.store(&entry{alias: alias, url: u, expiresAt: s.now().Add(s.ttl)})
+	case errors.Is(err, storage.ErrURLNotFound) && s.negativeTTL > 0:
+		s.store(&entry{alias: alias, notFound: true, expiresAt: s.now().Add(s.negativeTTL)})
+	}
+
+	return u, err
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	id, err := s.next.SaveURL(ctx, u)
+	if err == nil {
+		// the alias may be cached as missing
+		s.Invalidate(u.Alias)
+	}
+	return id, err
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL, allOrNothing bool) ([]int64, error) {
+	ids, err := s.next.SaveURLs(ctx, urls, allOrNothing)
+	if err == nil {
+		for i, u := range urls {
+			if ids[i] != 0 {
+				s.Invalidate(u.Alias)
+			}
+		}
+	}
+	return ids, err
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	err := s.next.DeleteURL(ctx, alias)
+	s.Invalidate(alias)
+	return err
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias, newURL string) error {
+	err := s.next.UpdateURL(ctx, alias, newURL)
+	s.Invalidate(alias)
+	return err
+}
+
+func (s *Storage) ListURLs(ctx context.Context, params storage.ListParams) ([]storage.URL, int64, error) {
+	return s.next.ListURLs(ctx, params)
+}
+
+// GetURLByTarget is not cached
+func (s *Storage) GetURLByTarget(ctx context.Context, ownerID int64, target string) (storage.URL, error) {
+	return s.next.GetURLByTarget(ctx, ownerID, target)
+}
+
+// Invalidate drops the alias from the cache
+func (s *Storage) Invalidate(alias string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[alias]; ok {
+		s.remove(el)
+	}
+}
+
+// Stats returns hit and miss counters
+func (s *Storage) Stats() Stats {
+	return Stats{
+		Hits:   s.hits.Load(),
+		Misses: s.misses.Load(),
+	}
+}
+
+// Len returns the number of cached aliases
+func (s *Storage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len()
+}
+
+func (s *Storage) lookup(alias string) (*entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[alias]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if !s.now().Before(e.expiresAt) {
+		s.remove(el)
+		return nil, false
+	}
+
+	s.lru.MoveToFront(el)
+
+	return e, true
+}
+
+func (s *Storage) store(e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[e.alias]; ok {
+		el.Value = e
+		s.lru.MoveToFront(el)
+		return
+	}
+
+	s.entries[e.alias] = s.lru.PushFront(e)
+
+	for s.lru.Len() > s.size {
+		s.remove(s.lru.Back())
+	}
+}
+
+func (s *Storage) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*entry).alias)
+}
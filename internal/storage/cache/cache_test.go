@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStorage counts GetURL calls that reach the real storage
+type countingStorage struct {
+	*memory.Storage
+	gets int
+}
+
+func (s *countingStorage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	s.gets++
+	return s.Storage.GetURL(ctx, alias)
+}
+
+func newTestCache(t *testing.T, size int) (*Storage, *countingStorage, *time.Time) {
+	t.Helper()
+
+	backend := &countingStorage{Storage: memory.New()}
+	c := New(backend, size, time.Minute, time.Second)
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	return c, backend, &now
+}
+
+func TestGetURLIsCached(t *testing.T) {
+	ctx := context.Background()
+	c, backend, now := newTestCache(t, 10)
+
+	_, err := c.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		u, err := c.GetURL(ctx, "google")
+		require.NoError(t, err)
+		assert.Equal(t, "https://www.google.com", u.URL)
+	}
+	assert.Equal(t, 1, backend.gets)
+	assert.Equal(t, Stats{Hits: 2, Misses: 1}, c.Stats())
+
+	// entry expires after ttl
+	*now = now.Add(time.Minute)
+	_, err = c.GetURL(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.gets)
+}
+
+func TestNegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	c, backend, now := newTestCache(t, 10)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.GetURL(ctx, "missing")
+		assert.ErrorIs(t, err, storage.ErrURLNotFound)
+	}
+	assert.Equal(t, 1, backend.gets)
+
+	// negative entries live shorter than found ones
+	*now = now.Add(time.Second)
+	_, err := c.GetURL(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+	assert.Equal(t, 2, backend.gets)
+
+	// saving the alias drops the negative entry
+	_, err = c.SaveURL(ctx, storage.URL{Alias: "missing", URL: "https://example.com"})
+	require.NoError(t, err)
+	u, err := c.GetURL(ctx, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", u.URL)
+}
+
+func TestDeleteInvalidates(t *testing.T) {
+	ctx := context.Background()
+	c, _, _ := newTestCache(t, 10)
+
+	_, err := c.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	require.NoError(t, err)
+	_, err = c.GetURL(ctx, "google")
+	require.NoError(t, err)
+
+	require.NoError(t, c.DeleteURL(ctx, "google"))
+
+	_, err = c.GetURL(ctx, "google")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestUpdateInvalidates(t *testing.T) {
+	ctx := context.Background()
+	c, _, _ := newTestCache(t, 10)
+
+	_, err := c.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	require.NoError(t, err)
+	_, err = c.GetURL(ctx, "google")
+	require.NoError(t, err)
+
+	require.NoError(t, c.UpdateURL(ctx, "google", "https://www.google.ru"))
+
+	u, err := c.GetURL(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.google.ru", u.URL)
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c, backend, _ := newTestCache(t, 2)
+
+	for _, alias := range []string{"a", "b", "c"} {
+		_, err := c.SaveURL(ctx, storage.URL{Alias: alias, URL: "https://example.com/" + alias})
+		require.NoError(t, err)
+	}
+
+	_, _ = c.GetURL(ctx, "a")
+	_, _ = c.GetURL(ctx, "b")
+	_, _ = c.GetURL(ctx, "a") // a is now more recent than b
+	_, _ = c.GetURL(ctx, "c") // evicts b
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, 3, backend.gets)
+
+	_, _ = c.GetURL(ctx, "a")
+	assert.Equal(t, 3, backend.gets)
+
+	_, _ = c.GetURL(ctx, "b")
+	assert.Equal(t, 4, backend.gets)
+}
@@ -0,0 +1,204 @@
+// Package instrument measures and traces every storage operation.
+package instrument
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"url-shortener/internal/storage"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Observer receives the duration and the result of every operation.
+// op is the name of the storage method.
+type Observer interface {
+	ObserveQuery(op string, err error, d time.Duration)
+}
+
+// Backend is everything the server asks of the storage: links, clicks,
+// expired links, api keys and health checks
+type Backend interface {
+	storage.URLStorage
+	SaveClicks(ctx context.Context, clicks []storage.Click) error
+	ClickStats(ctx context.Context, alias string, since time.Time) (storage.ClickStats, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+	ArchiveExpired(ctx context.Context, now time.Time) (int64, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error)
+	GetUser(ctx context.Context, id int64) (storage.User, error)
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
+}
+
+// Storage wraps another Backend, reports its calls to the observer
+// and starts a child span of the request for each of them.
+type Storage struct {
+	next     Backend
+	observer Observer
+	tracer   trace.Tracer
+}
+
+// observer and tracer may be nil when metrics or tracing are disabled.
+func New(next Backend, observer Observer, tracer trace.Tracer) *Storage {
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer("")
+	}
+
+	return &Storage{
+		next:     next,
+		observer: observer,
+		tracer:   tracer,
+	}
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	ctx, done := s.start(ctx, "SaveURL", attribute.String("alias", u.Alias))
+	id, err := s.next.SaveURL(ctx, u)
+	done(err)
+
+	return id, err
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	ctx, done := s.start(ctx, "GetURL", attribute.String("alias", alias))
+	u, err := s.next.GetURL(ctx, alias)
+	done(err)
+
+	return u, err
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	ctx, done := s.start(ctx, "DeleteURL", attribute.String("alias", alias))
+	err := s.next.DeleteURL(ctx, alias)
+	done(err)
+
+	return err
+}
+
+func (s *Storage) ListURLs(ctx context.Context, params storage.ListParams) ([]storage.URL, int64, error) {
+	ctx, done := s.start(ctx, "ListURLs")
+	urls, total, err := s.next.ListURLs(ctx, params)
+	done(err)
+
+	return urls, total, err
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias, newURL string) error {
+	ctx, done := s.start(ctx, "UpdateURL", attribute.String("alias", alias))
+	err := s.next.UpdateURL(ctx, alias, newURL)
+	done(err)
+
+	return err
+}
+
+func (s *Storage) GetURLByTarget(ctx context.Context, ownerID int64, target string) (storage.URL, error) {
+	ctx, done := s.start(ctx, "GetURLByTarget")
+	u, err := s.next.GetURLByTarget(ctx, ownerID, target)
+	done(err)
+
+	return u, err
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL, allOrNothing bool) ([]int64, error) {
+	ctx, done := s.start(ctx, "SaveURLs", attribute.Int("count", len(urls)))
+	ids, err := s.next.SaveURLs(ctx, urls, allOrNothing)
+	done(err)
+
+	return ids, err
+}
+
+func (s *Storage) SaveClicks(ctx context.Context, clicks []storage.Click) error {
+	ctx, done := s.start(ctx, "SaveClicks", attribute.Int("count", len(clicks)))
+	err := s.next.SaveClicks(ctx, clicks)
+	done(err)
+
+	return err
+}
+
+func (s *Storage) ClickStats(ctx context.Context, alias string, since time.Time) (storage.ClickStats, error) {
+	ctx, done := s.start(ctx, "ClickStats", attribute.String("alias", alias))
+	stats, err := s.next.ClickStats(ctx, alias, since)
+	done(err)
+
+	return stats, err
+}
+
+func (s *Storage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, done := s.start(ctx, "DeleteExpired")
+	n, err := s.next.DeleteExpired(ctx, now)
+	done(err)
+
+	return n, err
+}
+
+func (s *Storage) ArchiveExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, done := s.start(ctx, "ArchiveExpired")
+	n, err := s.next.ArchiveExpired(ctx, now)
+	done(err)
+
+	return n, err
+}
+
+// GetAPIKeyByHash does not put the hash into the span
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	ctx, done := s.start(ctx, "GetAPIKeyByHash")
+	k, err := s.next.GetAPIKeyByHash(ctx, hash)
+	done(err)
+
+	return k, err
+}
+
+func (s *Storage) GetUser(ctx context.Context, id int64) (storage.User, error) {
+	ctx, done := s.start(ctx, "GetUser", attribute.Int64("user_id", id))
+	u, err := s.next.GetUser(ctx, id)
+	done(err)
+
+	return u, err
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	ctx, done := s.start(ctx, "Ping")
+	err := s.next.Ping(ctx)
+	done(err)
+
+	return err
+}
+
+func (s *Storage) SchemaVersion(ctx context.Context) (int, error) {
+	ctx, done := s.start(ctx, "SchemaVersion")
+	v, err := s.next.SchemaVersion(ctx)
+	done(err)
+
+	return v, err
+}
+
+// start opens the span of op, done closes it and reports the result
+func (s *Storage) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, func(err error)) {
+	t1 := time.Now()
+	ctx, span := s.tracer.Start(ctx, "storage."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, attribute.String("db.operation.name", op))...),
+	)
+
+	return ctx, func(err error) {
+		if s.observer != nil {
+			s.observer.ObserveQuery(op, err, time.Since(t1))
+		}
+
+		// отсутствующая ссылка, ключ или пользователь и занятый алиас — обычный результат, не ошибка
+		switch {
+		case err == nil:
+		case errors.Is(err, storage.ErrURLNotFound), errors.Is(err, storage.ErrURLExists),
+			errors.Is(err, storage.ErrAPIKeyNotFound), errors.Is(err, storage.ErrUserNotFound):
+			span.SetAttributes(attribute.String("result", err.Error()))
+		default:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
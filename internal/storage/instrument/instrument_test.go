@@ -0,0 +1,129 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type query struct {
+	op  string
+	err error
+}
+
+type observerStub struct {
+	queries []query
+}
+
+func (o *observerStub) ObserveQuery(op string, err error, d time.Duration) {
+	o.queries = append(o.queries, query{op: op, err: err})
+}
+
+func TestObservesEveryCall(t *testing.T) {
+	ctx := context.Background()
+	observer := &observerStub{}
+	s := New(memory.New(), observer, nil)
+
+	_, err := s.SaveURL(ctx, storage.URL{Alias: "a", URL: "https://example.com"})
+	require.NoError(t, err)
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "a", URL: "https://example.com"})
+	require.ErrorIs(t, err, storage.ErrURLExists)
+	_, err = s.GetURL(ctx, "missing")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, _, err = s.ListURLs(ctx, storage.ListParams{})
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateURL(ctx, "a", "https://example.org"))
+	_, _ = s.GetURLByTarget(ctx, 0, "https://example.org")
+	_, err = s.SaveURLs(ctx, []storage.URL{{Alias: "b", URL: "https://example.com"}}, true)
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURL(ctx, "a"))
+
+	var ops []string
+	for _, q := range observer.queries {
+		ops = append(ops, q.op)
+	}
+	assert.Equal(t, []string{
+		"SaveURL", "SaveURL", "GetURL", "ListURLs", "UpdateURL", "GetURLByTarget", "SaveURLs", "DeleteURL",
+	}, ops)
+
+	assert.NoError(t, observer.queries[0].err)
+	assert.ErrorIs(t, observer.queries[1].err, storage.ErrURLExists)
+	assert.ErrorIs(t, observer.queries[2].err, storage.ErrURLNotFound)
+}
+
+func TestObservesBackendCalls(t *testing.T) {
+	ctx := context.Background()
+	observer := &observerStub{}
+	s := New(memory.New(), observer, nil)
+
+	now := time.Now()
+	require.NoError(t, s.SaveClicks(ctx, nil))
+	_, err := s.ClickStats(ctx, "missing", now)
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+	_, err = s.DeleteExpired(ctx, now)
+	require.NoError(t, err)
+	_, err = s.ArchiveExpired(ctx, now)
+	require.NoError(t, err)
+	_, err = s.GetAPIKeyByHash(ctx, "hash")
+	require.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+	_, err = s.GetUser(ctx, 1)
+	require.ErrorIs(t, err, storage.ErrUserNotFound)
+	require.NoError(t, s.Ping(ctx))
+	_, err = s.SchemaVersion(ctx)
+	require.NoError(t, err)
+
+	var ops []string
+	for _, q := range observer.queries {
+		ops = append(ops, q.op)
+	}
+	assert.Equal(t, []string{
+		"SaveClicks", "ClickStats", "DeleteExpired", "ArchiveExpired", "GetAPIKeyByHash", "GetUser", "Ping", "SchemaVersion",
+	}, ops)
+}
+
+type failingStorage struct {
+	Backend
+}
+
+func (failingStorage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	return storage.URL{}, errors.New("disk I/O error")
+}
+
+func TestStartsChildSpans(t *testing.T) {
+	ctx := context.Background()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	ctx, parent := tracer.Start(ctx, "GET /{alias}")
+
+	s := New(memory.New(), nil, tracer)
+	_, err := s.GetURL(ctx, "missing")
+	require.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	_, err = New(failingStorage{}, nil, tracer).GetURL(ctx, "google")
+	require.Error(t, err)
+
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	missing, failed := spans[0], spans[1]
+	assert.Equal(t, "storage.GetURL", missing.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), missing.Parent().SpanID())
+	assert.Equal(t, codes.Unset, missing.Status().Code)
+
+	assert.Equal(t, parent.SpanContext().TraceID(), failed.SpanContext().TraceID())
+	assert.Equal(t, codes.Error, failed.Status().Code)
+}
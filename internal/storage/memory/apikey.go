@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+// SaveAPIKey stores a new api key and returns its id
+func (s *Storage) SaveAPIKey(ctx context.Context, k storage.APIKey) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastKeyID++
+	k.ID = s.lastKeyID
+	if k.CreatedAt.IsZero() {
+		k.CreatedAt = time.Now()
+	}
+	s.apiKeys = append(s.apiKeys, k)
+
+	return k.ID, nil
+}
+
+// GetAPIKeyByHash finds a key by the hash of its value, revoked keys are returned too
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.apiKeys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+
+	return storage.APIKey{}, storage.ErrAPIKeyNotFound
+}
+
+// ListAPIKeys returns all keys ordered by id
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]storage.APIKey(nil), s.apiKeys...), nil
+}
+
+// RevokeAPIKey marks an active key as revoked
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, k := range s.apiKeys {
+		if k.ID == id && !k.Revoked() {
+			s.apiKeys[i].RevokedAt = at
+			return nil
+		}
+	}
+
+	return storage.ErrAPIKeyNotFound
+}
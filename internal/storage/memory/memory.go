@@ -0,0 +1,353 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"url-shortener/internal/lib/urlnorm"
+	"url-shortener/internal/storage"
+)
+
+// Storage keeps urls in a map guarded by a mutex.
+// Data is lost on restart, so it is meant for tests and demo instances.
+type Storage struct {
+	mu   sync.RWMutex
+	urls map[string]storage.URL
+	// aliases indexes urls by id, so clicks are matched to links without a scan
+	aliases  map[int64]string
+	archived []storage.URL
+	// clicks counts redirects per link id and UTC day,
+	// raw clicks are not kept so memory grows with links, not traffic
+	clicks map[int64]map[string]int64
+	lastID int64
+
+	apiKeys   []storage.APIKey
+	lastKeyID int64
+
+	users      []storage.User
+	lastUserID int64
+}
+
+// dayFormat is the key of per-day click counts
+const dayFormat = "2006-01-02"
+
+func New() *Storage {
+	return &Storage{
+		urls:    make(map[string]storage.URL),
+		aliases: make(map[int64]string),
+		clicks:  make(map[int64]map[string]int64),
+	}
+}
+
+// Close does nothing, it is here to match the database storages
+func (s *Storage) Close() error {
+	return nil
+}
+
+// Ping always succeeds, the storage lives in the process
+func (s *Storage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// SchemaVersion is zero, there are no migrations
+func (s *Storage) SchemaVersion(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// SaveURL saves url under the alias and returns id of the new record
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.urls[u.Alias]; ok {
+		return 0, storage.ErrURLExists
+	}
+
+	s.lastID++
+	u.ID = s.lastID
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	s.urls[u.Alias] = u
+	s.aliases[u.ID] = u.Alias
+
+	return u.ID, nil
+}
+
+// SaveURLs saves links at once and returns their ids in order,
+// zero for links whose alias is taken. With allOrNothing nothing is saved
+// if any alias is taken.
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL, allOrNothing bool) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]int64, len(urls))
+	batch := make(map[string]struct{}, len(urls))
+	taken := false
+	for i, u := range urls {
+		_, exists := s.urls[u.Alias]
+		_, dup := batch[u.Alias]
+		if exists || dup {
+			taken = true
+			continue
+		}
+		batch[u.Alias] = struct{}{}
+		ids[i] = s.lastID + int64(len(batch))
+	}
+
+	if taken && allOrNothing {
+		return ids, storage.ErrURLExists
+	}
+
+	now := time.Now()
+	for i, u := range urls {
+		if ids[i] == 0 {
+			continue
+		}
+		u.ID = ids[i]
+		if u.CreatedAt.IsZero() {
+			u.CreatedAt = now
+		}
+		s.urls[u.Alias] = u
+		s.aliases[u.ID] = u.Alias
+	}
+	s.lastID += int64(len(batch))
+
+	return ids, nil
+}
+
+// GetURL retrieves a URL by its alias
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.urls[alias]
+	if !ok {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	return u, nil
+}
+
+// DeleteURL deletes a URL by its alias
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.urls[alias]
+	if !ok {
+		return storage.ErrURLNotFound
+	}
+
+	s.remove(u)
+
+	return nil
+}
+
+// ListURLs returns a page of links and the number of links matching params
+func (s *Storage) ListURLs(ctx context.Context, params storage.ListParams) ([]storage.URL, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := strings.ToLower(params.Query)
+	matched := make([]storage.URL, 0, len(s.urls))
+	for _, u := range s.urls {
+		if params.OwnerID != 0 && u.OwnerID != params.OwnerID {
+			continue
+		}
+		if query != "" &&
+			!strings.Contains(strings.ToLower(u.Alias), query) &&
+			!strings.Contains(strings.ToLower(u.URL), query) {
+			continue
+		}
+		matched = append(matched, u)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if params.Desc {
+			a, b = b, a
+		}
+		switch params.SortBy {
+		case storage.SortByAlias:
+			if a.Alias != b.Alias {
+				return a.Alias < b.Alias
+			}
+		case storage.SortByURL:
+			if a.URL != b.URL {
+				return a.URL < b.URL
+			}
+		case storage.SortByCreatedAt:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		}
+		return a.ID < b.ID
+	})
+
+	total := int64(len(matched))
+	if params.Offset >= len(matched) {
+		return []storage.URL{}, total, nil
+	}
+	matched = matched[params.Offset:]
+	if params.Limit > 0 && params.Limit < len(matched) {
+		matched = matched[:params.Limit]
+	}
+
+	return matched, total, nil
+}
+
+// UpdateURL changes the target url of the alias
+func (s *Storage) UpdateURL(ctx context.Context, alias, newURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.urls[alias]
+	if !ok {
+		return storage.ErrURLNotFound
+	}
+
+	u.URL = newURL
+	s.urls[alias] = u
+
+	return nil
+}
+
+// ReplaceURL overwrites every field of the link with the same alias.
+// The link keeps its id, so its clicks stay with it.
+func (s *Storage) ReplaceURL(ctx context.Context, u storage.URL) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.urls[u.Alias]
+	if !ok {
+		return 0, storage.ErrURLNotFound
+	}
+
+	u.ID = old.ID
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	s.urls[u.Alias] = u
+
+	return u.ID, nil
+}
+
+// GetURLByTarget returns the oldest link of the owner to the same url
+// after normalization, links that expire are skipped
+func (s *Storage) GetURLByTarget(ctx context.Context, ownerID int64, target string) (storage.URL, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hash := urlnorm.Hash(target)
+
+	var (
+		found storage.URL
+		ok    bool
+	)
+	for _, u := range s.urls {
+		if u.OwnerID != ownerID || !u.ExpiresAt.IsZero() || urlnorm.Hash(u.URL) != hash {
+			continue
+		}
+		if !ok || u.ID < found.ID {
+			found, ok = u, true
+		}
+	}
+	if !ok {
+		return storage.URL{}, storage.ErrURLNotFound
+	}
+
+	return found, nil
+}
+
+// DeleteExpired deletes links that expired before now and returns how many were removed
+func (s *Storage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, u := range s.urls {
+		if u.Expired(now) {
+			s.remove(u)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// ArchiveExpired moves links that expired before now to the archive
+// and returns how many were moved
+func (s *Storage) ArchiveExpired(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, u := range s.urls {
+		if u.Expired(now) {
+			s.archived = append(s.archived, u)
+			// статистика архивных ссылок недоступна
+			s.remove(u)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// remove deletes the link with its clicks, the caller holds the lock
+func (s *Storage) remove(u storage.URL) {
+	delete(s.urls, u.Alias)
+	delete(s.aliases, u.ID)
+	delete(s.clicks, u.ID)
+}
+
+// SaveClicks adds a batch of clicks to the per-day counts.
+// Clicks on links that no longer exist are dropped.
+func (s *Storage) SaveClicks(ctx context.Context, clicks []storage.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range clicks {
+		if _, ok := s.aliases[c.URLID]; !ok {
+			continue
+		}
+		days, ok := s.clicks[c.URLID]
+		if !ok {
+			days = make(map[string]int64)
+			s.clicks[c.URLID] = days
+		}
+		days[c.ClickedAt.UTC().Format(dayFormat)]++
+	}
+
+	return nil
+}
+
+// ClickStats returns total clicks on the link and per-day counts since the given moment.
+// Only days are kept, so since is rounded down to the start of its UTC day.
+func (s *Storage) ClickStats(ctx context.Context, alias string, since time.Time) (storage.ClickStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.urls[alias]
+	if !ok {
+		return storage.ClickStats{}, storage.ErrURLNotFound
+	}
+
+	var stats storage.ClickStats
+	from := since.UTC().Format(dayFormat)
+	for day, count := range s.clicks[u.ID] {
+		stats.Total += count
+		// дни в формате 2006-01-02 сравниваются как строки
+		if day >= from {
+			stats.Daily = append(stats.Daily, storage.DailyClicks{Day: day, Count: count})
+		}
+	}
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Day < stats.Daily[j].Day
+	})
+
+	return stats, nil
+}
@@ -0,0 +1,306 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"url-shortener/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+
+	s := New()
+
+	id, err := s.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com", RedirectCode: 301})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	assert.ErrorIs(t, err, storage.ErrURLExists)
+
+	got, err := s.GetURL(ctx, "google")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), got.CreatedAt, time.Minute)
+	got.CreatedAt = time.Time{}
+	assert.Equal(t, storage.URL{ID: id, Alias: "google", URL: "https://www.google.com", RedirectCode: 301}, got)
+
+	require.NoError(t, s.UpdateURL(ctx, "google", "https://www.google.ru"))
+	assert.ErrorIs(t, s.UpdateURL(ctx, "missing", "https://example.com"), storage.ErrURLNotFound)
+	got, err = s.GetURL(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.google.ru", got.URL)
+
+	_, err = s.GetURL(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	require.NoError(t, s.DeleteURL(ctx, "google"))
+	assert.ErrorIs(t, s.DeleteURL(ctx, "google"), storage.ErrURLNotFound)
+
+	_, err = s.GetURL(ctx, "google")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestStorageConcurrentSave(t *testing.T) {
+	ctx := context.Background()
+
+	s := New()
+
+	const workers = 50
+
+	var wg sync.WaitGroup
+	ids := make(chan int64, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := s.SaveURL(ctx, storage.URL{Alias: fmt.Sprintf("alias%d", i), URL: "https://www.google.com"})
+			assert.NoError(t, err)
+			ids <- id
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int64]bool)
+	for id := range ids {
+		assert.False(t, seen[id], "duplicate id %d", id)
+		seen[id] = true
+	}
+	assert.Len(t, seen, workers)
+}
+
+func TestListURLs(t *testing.T) {
+	ctx := context.Background()
+
+	s := New()
+	for _, u := range []storage.URL{
+		{Alias: "b_go", URL: "https://go.dev"},
+		{Alias: "a_google", URL: "https://www.google.com"},
+		{Alias: "c_mail", URL: "https://mail.ru"},
+	} {
+		_, err := s.SaveURL(ctx, u)
+		require.NoError(t, err)
+	}
+
+	urls, total, err := s.ListURLs(ctx, storage.ListParams{Limit: 2, SortBy: storage.SortByAlias, Desc: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, urls, 2)
+	assert.Equal(t, "c_mail", urls[0].Alias)
+	assert.Equal(t, "b_go", urls[1].Alias)
+
+	urls, total, err = s.ListURLs(ctx, storage.ListParams{Limit: 10, Query: "GO"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, urls, 2)
+	assert.Equal(t, "b_go", urls[0].Alias)
+
+	urls, _, err = s.ListURLs(ctx, storage.ListParams{Limit: 10, Offset: 3})
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func TestGetURLByTarget(t *testing.T) {
+	ctx := context.Background()
+
+	s := New()
+
+	alice, err := s.SaveUser(ctx, storage.User{Name: "alice"})
+	require.NoError(t, err)
+	bob, err := s.SaveUser(ctx, storage.User{Name: "bob"})
+	require.NoError(t, err)
+
+	for _, u := range []storage.URL{
+		{Alias: "first", URL: "https://Example.com:443/?b=2&a=1", OwnerID: alice},
+		{Alias: "second", URL: "https://example.com/?a=1&b=2", OwnerID: alice},
+		{Alias: "expiring", URL: "https://go.dev", OwnerID: alice, ExpiresAt: time.Now().Add(time.Hour)},
+		{Alias: "bobs", URL: "https://example.com/?a=1&b=2", OwnerID: bob},
+		{Alias: "legacy", URL: "https://example.com/?a=1&b=2"},
+	} {
+		_, err := s.SaveURL(ctx, u)
+		require.NoError(t, err)
+	}
+
+	got, err := s.GetURLByTarget(ctx, alice, "https://EXAMPLE.com?a=1&b=2")
+	require.NoError(t, err)
+	assert.Equal(t, "first", got.Alias)
+
+	got, err = s.GetURLByTarget(ctx, bob, "https://example.com/?b=2&a=1")
+	require.NoError(t, err)
+	assert.Equal(t, "bobs", got.Alias)
+
+	got, err = s.GetURLByTarget(ctx, 0, "https://example.com/?b=2&a=1")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", got.Alias)
+
+	_, err = s.GetURLByTarget(ctx, alice, "https://go.dev")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// после смены адреса ссылка находится по новому
+	require.NoError(t, s.UpdateURL(ctx, "expiring", "https://example.org"))
+	require.NoError(t, s.UpdateURL(ctx, "second", "https://example.org"))
+	_, err = s.GetURLByTarget(ctx, alice, "http://example.org:80")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+	got, err = s.GetURLByTarget(ctx, alice, "https://example.org")
+	require.NoError(t, err)
+	assert.Equal(t, "second", got.Alias)
+}
+
+func TestSaveURLs(t *testing.T) {
+	ctx := context.Background()
+
+	s := New()
+	var err error
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "taken", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	batch := []storage.URL{
+		{Alias: "one", URL: "https://example.com/1"},
+		{Alias: "taken", URL: "https://example.com/2"},
+		{Alias: "two", URL: "https://example.com/3", RedirectCode: 301},
+		{Alias: "one", URL: "https://example.com/4"},
+	}
+
+	// все или ничего: ничего не сохранено, нули отмечают занятые алиасы
+	ids, err := s.SaveURLs(ctx, batch, true)
+	assert.ErrorIs(t, err, storage.ErrURLExists)
+	require.Len(t, ids, 4)
+	assert.NotZero(t, ids[0])
+	assert.Zero(t, ids[1])
+	assert.Zero(t, ids[3])
+	_, err = s.GetURL(ctx, "one")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// по возможности: сохраняется всё, что можно
+	ids, err = s.SaveURLs(ctx, batch, false)
+	require.NoError(t, err)
+	require.Len(t, ids, 4)
+	assert.Zero(t, ids[1])
+	assert.Zero(t, ids[3])
+
+	got, err := s.GetURL(ctx, "one")
+	require.NoError(t, err)
+	assert.Equal(t, ids[0], got.ID)
+	assert.Equal(t, "https://example.com/1", got.URL)
+
+	got, err = s.GetURL(ctx, "two")
+	require.NoError(t, err)
+	assert.Equal(t, ids[2], got.ID)
+	assert.Equal(t, 301, got.RedirectCode)
+	assert.WithinDuration(t, time.Now(), got.CreatedAt, time.Minute)
+
+	// переходы по ссылкам из пакета находят свою ссылку
+	assert.Equal(t, "one", s.aliases[ids[0]])
+	assert.Equal(t, "two", s.aliases[ids[2]])
+	assert.Len(t, s.aliases, 3)
+}
+
+func TestReplaceURL(t *testing.T) {
+	ctx := context.Background()
+
+	s := New()
+
+	id, err := s.SaveURL(ctx, storage.URL{Alias: "link", URL: "https://old.example", RedirectCode: 301})
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	createdAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	got, err := s.ReplaceURL(ctx, storage.URL{Alias: "link", URL: "https://new.example", ExpiresAt: expiresAt, CreatedAt: createdAt})
+	require.NoError(t, err)
+	assert.Equal(t, id, got)
+
+	u, err := s.GetURL(ctx, "link")
+	require.NoError(t, err)
+	assert.Equal(t, id, u.ID)
+	assert.Equal(t, "https://new.example", u.URL)
+	assert.Zero(t, u.RedirectCode)
+	assert.True(t, expiresAt.Equal(u.ExpiresAt))
+	assert.True(t, createdAt.Equal(u.CreatedAt))
+
+	_, err = s.ReplaceURL(ctx, storage.URL{Alias: "missing", URL: "https://new.example"})
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestClickStats(t *testing.T) {
+	ctx := context.Background()
+
+	s := New()
+
+	id, err := s.SaveURL(ctx, storage.URL{Alias: "link", URL: "https://example.com"})
+	require.NoError(t, err)
+	otherID, err := s.SaveURL(ctx, storage.URL{Alias: "other", URL: "https://example.org"})
+	require.NoError(t, err)
+
+	day := func(d, h int) time.Time { return time.Date(2024, 5, d, h, 0, 0, 0, time.UTC) }
+	require.NoError(t, s.SaveClicks(ctx, []storage.Click{
+		{URLID: id, ClickedAt: day(1, 10)},
+		{URLID: id, ClickedAt: day(2, 9)},
+		{URLID: id, ClickedAt: day(2, 23)},
+		{URLID: id, ClickedAt: day(3, 0)},
+		{URLID: otherID, ClickedAt: day(2, 12)},
+		// ссылка уже удалена
+		{URLID: 100, ClickedAt: day(2, 12)},
+	}))
+	assert.Len(t, s.clicks, 2)
+
+	stats, err := s.ClickStats(ctx, "link", day(2, 15))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stats.Total)
+	assert.Equal(t, []storage.DailyClicks{
+		{Day: "2024-05-02", Count: 2},
+		{Day: "2024-05-03", Count: 1},
+	}, stats.Daily)
+
+	_, err = s.ClickStats(ctx, "missing", day(1, 0))
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// счётчики удаляются вместе со ссылкой
+	require.NoError(t, s.DeleteURL(ctx, "link"))
+	assert.NotContains(t, s.clicks, id)
+	assert.NotContains(t, s.aliases, id)
+
+	require.NoError(t, s.SaveClicks(ctx, []storage.Click{{URLID: id, ClickedAt: day(4, 0)}}))
+	assert.NotContains(t, s.clicks, id)
+}
+
+func TestExpiredLinksDropClicks(t *testing.T) {
+	cleanups := map[string]func(s *Storage, ctx context.Context, now time.Time) (int64, error){
+		"DeleteExpired":  (*Storage).DeleteExpired,
+		"ArchiveExpired": (*Storage).ArchiveExpired,
+	}
+
+	for name, cleanup := range cleanups {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+
+			s := New()
+
+			expired, err := s.SaveURL(ctx, storage.URL{Alias: "expired", URL: "https://example.com", ExpiresAt: now.Add(-time.Hour)})
+			require.NoError(t, err)
+			alive, err := s.SaveURL(ctx, storage.URL{Alias: "alive", URL: "https://example.com"})
+			require.NoError(t, err)
+
+			require.NoError(t, s.SaveClicks(ctx, []storage.Click{
+				{URLID: expired, ClickedAt: now},
+				{URLID: alive, ClickedAt: now},
+			}))
+
+			n, err := cleanup(s, ctx, now)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), n)
+
+			assert.NotContains(t, s.clicks, expired)
+			assert.Contains(t, s.clicks, alive)
+			assert.Equal(t, map[int64]string{alive: "alive"}, s.aliases)
+		})
+	}
+}
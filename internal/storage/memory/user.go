@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+// SaveUser creates a user and returns its id
+func (s *Storage) SaveUser(ctx context.Context, u storage.User) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Name == u.Name {
+			return 0, storage.ErrUserExists
+		}
+	}
+
+	s.lastUserID++
+	u.ID = s.lastUserID
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	if u.Role == "" {
+		u.Role = storage.RoleViewer
+	}
+	s.users = append(s.users, u)
+
+	return u.ID, nil
+}
+
+// GetUser finds a user by id
+func (s *Storage) GetUser(ctx context.Context, id int64) (storage.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+
+	return storage.User{}, storage.ErrUserNotFound
+}
+
+// GetUserByName finds a user by name
+func (s *Storage) GetUserByName(ctx context.Context, name string) (storage.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Name == name {
+			return u, nil
+		}
+	}
+
+	return storage.User{}, storage.ErrUserNotFound
+}
+
+// SetUserRole changes the role of the user
+func (s *Storage) SetUserRole(ctx context.Context, id int64, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, u := range s.users {
+		if u.ID == id {
+			s.users[i].Role = role
+			return nil
+		}
+	}
+
+	return storage.ErrUserNotFound
+}
+
+// ListUsers returns all users ordered by id
+func (s *Storage) ListUsers(ctx context.Context) ([]storage.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]storage.User(nil), s.users...), nil
+}
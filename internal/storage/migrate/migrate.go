@@ -0,0 +1,308 @@
+// Package migrate applies numbered SQL migrations and records them
+// in the schema_migrations table.
+//
+// Migrations are read from an fs.FS (usually embedded) where every version
+// has a pair of files: 0001_create_url.up.sql and 0001_create_url.down.sql.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoMigrations = errors.New("no migrations to roll back")
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes one known migration and whether it is applied
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New reads migrations from the root of fsys.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	const op = "storage.migrate.New"
+
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies all pending migrations in order and returns how many were applied
+func (m *Migrator) Up() (int, error) {
+	const op = "storage.migrate.Up"
+
+	applied, err := m.applied()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	count := 0
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+
+		err := m.inTx(mig.Up, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+			mig.Version, mig.Name, time.Now().UTC())
+		if err != nil {
+			return count, fmt.Errorf("%s: apply %04d_%s: %w", op, mig.Version, mig.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Baseline records migrations up to version as applied without running them.
+// It is meant for databases whose schema was created before migrations existed.
+// Returns how many migrations were recorded.
+func (m *Migrator) Baseline(version int) (int, error) {
+	const op = "storage.migrate.Baseline"
+
+	applied, err := m.applied()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	count := 0
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+
+		err := m.inTx("", "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+			mig.Version, mig.Name, time.Now().UTC())
+		if err != nil {
+			return count, fmt.Errorf("%s: record %04d_%s: %w", op, mig.Version, mig.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Down rolls back the latest applied migration and returns it
+func (m *Migrator) Down() (Migration, error) {
+	const op = "storage.migrate.Down"
+
+	applied, err := m.applied()
+	if err != nil {
+		return Migration{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+
+		err := m.inTx(mig.Down, "DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+		if err != nil {
+			return Migration{}, fmt.Errorf("%s: roll back %04d_%s: %w", op, mig.Version, mig.Name, err)
+		}
+
+		return mig, nil
+	}
+
+	return Migration{}, ErrNoMigrations
+}
+
+// Status lists all known migrations
+func (m *Migrator) Status() ([]Status, error) {
+	const op = "storage.migrate.Status"
+
+	applied, err := m.applied()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		at, ok := applied[mig.Version]
+		res = append(res, Status{
+			Version:   mig.Version,
+			Name:      mig.Name,
+			Applied:   ok,
+			AppliedAt: at,
+		})
+	}
+
+	return res, nil
+}
+
+// Version returns the latest applied version, zero for an empty database
+func (m *Migrator) Version() (int, error) {
+	const op = "storage.migrate.Version"
+
+	applied, err := m.applied()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+
+	return version, nil
+}
+
+// Current returns the latest applied version without creating
+// schema_migrations, so it is safe for health checks on a live database.
+func Current(ctx context.Context, db *sql.DB) (int, error) {
+	const op = "storage.migrate.Current"
+
+	var version int
+	if err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// applied creates schema_migrations if needed and returns applied versions
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	_, err := m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	rows, err := m.db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// inTx runs the migration script and the bookkeeping query in one transaction
+func (m *Migrator) inTx(script, query string, args ...any) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if strings.TrimSpace(script) != "" {
+		if _, err := tx.Exec(script); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// load parses <version>_<name>.up.sql and <version>_<name>.down.sql files
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" {
+			continue
+		}
+
+		base := strings.TrimSuffix(e.Name(), ".sql")
+		direction := path.Ext(base)
+		if direction != ".up" && direction != ".down" {
+			return nil, fmt.Errorf("migration %q: want .up.sql or .down.sql suffix", e.Name())
+		}
+		base = strings.TrimSuffix(base, direction)
+
+		rawVersion, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %q: want <version>_<name> file name", e.Name())
+		}
+		version, err := strconv.Atoi(rawVersion)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %q: invalid version %q", e.Name(), rawVersion)
+		}
+
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: name}
+			byVersion[version] = mig
+		}
+		if mig.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, mig.Name, name)
+		}
+
+		if direction == ".up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
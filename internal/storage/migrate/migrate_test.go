@@ -0,0 +1,150 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER); CREATE INDEX idx_b ON b (id);")},
+		"0002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	}
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var n int
+	err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+	require.NoError(t, err)
+
+	return n == 1
+}
+
+func TestMigrator(t *testing.T) {
+	db := openDB(t)
+
+	m, err := New(db, testFS())
+	require.NoError(t, err)
+
+	n, err := m.Up()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.True(t, tableExists(t, db, "a"))
+	assert.True(t, tableExists(t, db, "b"))
+
+	// second run is a no-op
+	n, err = m.Up()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	version, err = Current(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	mig, err := m.Down()
+	require.NoError(t, err)
+	assert.Equal(t, 2, mig.Version)
+	assert.False(t, tableExists(t, db, "b"))
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[0].AppliedAt.IsZero())
+	assert.Equal(t, "create_b", statuses[1].Name)
+	assert.False(t, statuses[1].Applied)
+
+	_, err = m.Down()
+	require.NoError(t, err)
+	_, err = m.Down()
+	assert.ErrorIs(t, err, ErrNoMigrations)
+}
+
+func TestMigratorFailedMigrationIsNotRecorded(t *testing.T) {
+	db := openDB(t)
+
+	fsys := testFS()
+	fsys["0003_broken.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE c (id INTEGER); NOT SQL;")}
+	fsys["0003_broken.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE c;")}
+
+	m, err := New(db, fsys)
+	require.NoError(t, err)
+
+	n, err := m.Up()
+	require.Error(t, err)
+	assert.Equal(t, 2, n)
+	assert.False(t, tableExists(t, db, "c"))
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+}
+
+func TestMigratorBaseline(t *testing.T) {
+	db := openDB(t)
+
+	// таблица a уже создана без миграций
+	_, err := db.Exec("CREATE TABLE a (id INTEGER);")
+	require.NoError(t, err)
+
+	m, err := New(db, testFS())
+	require.NoError(t, err)
+
+	n, err := m.Baseline(1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// повторный вызов ничего не записывает
+	n, err = m.Baseline(1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = m.Up()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.True(t, tableExists(t, db, "b"))
+
+	version, err := m.Version()
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	cases := []string{
+		"create_a.up.sql",
+		"0001_create_a.sql",
+		"x001_create_a.up.sql",
+	}
+
+	for _, name := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := load(fstest.MapFS{name: {Data: []byte("SELECT 1;")}})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"url-shortener/internal/storage"
+)
+
+const apiKeyColumns = "id, user_id, name, prefix, hash, scopes, created_at, revoked_at"
+
+// SaveAPIKey stores a new api key and returns its id
+func (s *Storage) SaveAPIKey(ctx context.Context, k storage.APIKey) (int64, error) {
+	const op = "storage.postgres.SaveAPIKey"
+
+	createdAt := k.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO api_keys (user_id, name, prefix, hash, scopes, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		nullID(k.UserID), k.Name, k.Prefix, k.Hash, strings.Join(k.Scopes, ","), createdAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetAPIKeyByHash finds a key by the hash of its value, revoked keys are returned too
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	const op = "storage.postgres.GetAPIKeyByHash"
+
+	k, err := scanAPIKey(s.db.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE hash = $1", hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.APIKey{}, storage.ErrAPIKeyNotFound
+		}
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return k, nil
+}
+
+// ListAPIKeys returns all keys ordered by id
+func (s *Storage) ListAPIKeys(ctx context.Context) ([]storage.APIKey, error) {
+	const op = "storage.postgres.ListAPIKeys"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []storage.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey marks an active key as revoked
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64, at time.Time) error {
+	const op = "storage.postgres.RevokeAPIKey"
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL", at, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func scanAPIKey(row rowScanner) (storage.APIKey, error) {
+	var (
+		k         storage.APIKey
+		scopes    string
+		createdAt sql.NullTime
+		revokedAt sql.NullTime
+		userID    sql.NullInt64
+	)
+	if err := row.Scan(&k.ID, &userID, &k.Name, &k.Prefix, &k.Hash, &scopes, &createdAt, &revokedAt); err != nil {
+		return storage.APIKey{}, err
+	}
+	if scopes != "" {
+		k.Scopes = strings.Split(scopes, ",")
+	}
+	k.UserID = userID.Int64
+	k.CreatedAt = createdAt.Time
+	k.RevokedAt = revokedAt.Time
+
+	return k, nil
+}
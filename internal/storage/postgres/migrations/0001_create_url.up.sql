@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS url (
+	id BIGSERIAL PRIMARY KEY,
+	alias TEXT NOT NULL UNIQUE,
+	url TEXT NOT NULL,
+	redirect_code INTEGER NOT NULL DEFAULT 0);
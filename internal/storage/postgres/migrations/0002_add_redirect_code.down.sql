@@ -0,0 +1,2 @@
+-- nothing to undo, see 0002_add_redirect_code.up.sql
+SELECT 1;
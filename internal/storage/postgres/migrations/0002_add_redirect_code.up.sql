@@ -0,0 +1,4 @@
+-- redirect_code is created by 0001 on postgres. The migration keeps the
+-- numbering of sqlite, where it was added later, so one schema version
+-- means the same schema on every backend.
+SELECT 1;
@@ -0,0 +1,11 @@
+ALTER TABLE url ADD COLUMN expires_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_url_expires_at ON url (expires_at);
+
+CREATE TABLE IF NOT EXISTS url_archive (
+	id BIGSERIAL PRIMARY KEY,
+	url_id BIGINT NOT NULL,
+	alias TEXT NOT NULL,
+	url TEXT NOT NULL,
+	redirect_code INTEGER NOT NULL DEFAULT 0,
+	expires_at TIMESTAMPTZ,
+	archived_at TIMESTAMPTZ NOT NULL);
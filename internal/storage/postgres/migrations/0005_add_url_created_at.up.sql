@@ -0,0 +1,3 @@
+-- NULL for links saved before created_at was recorded
+ALTER TABLE url ADD COLUMN created_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_url_created_at ON url (created_at);
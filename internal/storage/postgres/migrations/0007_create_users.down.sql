@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_url_owner_id;
+ALTER TABLE url DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS user_id;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS users (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now());
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS user_id BIGINT REFERENCES users (id);
+-- links saved before owners were recorded stay without one, only admins manage them
+ALTER TABLE url ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES users (id);
+CREATE INDEX IF NOT EXISTS idx_url_owner_id ON url (owner_id);
+-- keys created before users existed had access to every link, keep it by giving them an admin
+INSERT INTO users (name, is_admin)
+	SELECT 'admin', TRUE WHERE EXISTS (SELECT 1 FROM api_keys);
+UPDATE api_keys SET user_id = (SELECT id FROM users WHERE name = 'admin') WHERE user_id IS NULL;
@@ -0,0 +1,4 @@
+-- users could create and manage their own links before roles, so they become editors
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'viewer';
+UPDATE users SET role = CASE WHEN is_admin THEN 'admin' ELSE 'editor' END;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
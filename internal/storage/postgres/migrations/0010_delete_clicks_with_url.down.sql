@@ -0,0 +1 @@
+ALTER TABLE clicks DROP CONSTRAINT IF EXISTS clicks_url_id_fkey;
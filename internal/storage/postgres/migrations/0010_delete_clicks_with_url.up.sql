@@ -0,0 +1,5 @@
+-- clicks go away together with their link, also when expired links
+-- are deleted or moved to url_archive
+DELETE FROM clicks WHERE url_id NOT IN (SELECT id FROM url);
+ALTER TABLE clicks ADD CONSTRAINT clicks_url_id_fkey
+	FOREIGN KEY (url_id) REFERENCES url (id) ON DELETE CASCADE;
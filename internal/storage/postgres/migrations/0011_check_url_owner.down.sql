@@ -0,0 +1,2 @@
+-- nothing to undo, see 0011_check_url_owner.up.sql
+SELECT 1;
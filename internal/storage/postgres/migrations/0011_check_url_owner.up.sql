@@ -0,0 +1,3 @@
+-- url.owner_id has a foreign key to users since 0007. The migration keeps
+-- the numbering of sqlite, where triggers do the same check.
+SELECT 1;
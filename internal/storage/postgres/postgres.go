@@ -0,0 +1,540 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+	"url-shortener/internal/lib/urlnorm"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrate"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // init postgres driver
+)
+
+// SQLSTATE codes postgres returns for violated constraints
+const (
+	uniqueViolation = "23505"
+	// foreignKeyViolation is returned for an owner_id with no such user
+	foreignKeyViolation = "23503"
+)
+
+type Storage struct {
+	db *sql.DB
+}
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Open connects to the database without touching its schema
+func Open(dsn string) (*Storage, error) {
+	const op = "storage.postgres.Open"
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{db: db}, nil
+}
+
+// New connects to the database and applies pending migrations
+func New(dsn string) (*Storage, error) {
+	const op = "storage.postgres.New"
+	s, err := Open(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	m, err := s.Migrator()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := m.Up(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// Close closes the database
+func (s *Storage) Close() error {
+	const op = "storage.postgres.Close"
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Ping checks that the database answers
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgres.Ping"
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the latest applied migration
+func (s *Storage) SchemaVersion(ctx context.Context) (int, error) {
+	const op = "storage.postgres.SchemaVersion"
+	version, err := migrate.Current(ctx, s.db)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// Migrator returns migrator over the embedded postgres migrations
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(s.db, fsys)
+}
+
+// SaveURL saves url under the alias and returns id of the new row
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	const op = "storage.postgres.SaveURL"
+
+	createdAt := u.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO url (url, alias, redirect_code, expires_at, created_at, owner_id, url_hash) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		u.URL, u.Alias, u.RedirectCode, nullTime(u.ExpiresAt), createdAt, nullID(u.OwnerID), urlnorm.Hash(u.URL),
+	).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return 0, storage.ErrURLExists
+		}
+		if isOwnerMissing(err) {
+			return 0, storage.ErrUserNotFound
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// isOwnerMissing reports whether the link was rejected by the foreign key
+// of url.owner_id, the only foreign key of the url table
+func isOwnerMissing(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
+
+// SaveURLs saves links in one transaction and returns their ids in order,
+// zero for links whose alias is taken. With allOrNothing nothing is saved
+// if any alias is taken.
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL, allOrNothing bool) ([]int64, error) {
+	const op = "storage.postgres.SaveURLs"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// ON CONFLICT keeps the transaction usable after a taken alias
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO url (url, alias, redirect_code, expires_at, created_at, owner_id, url_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (alias) DO NOTHING RETURNING id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	ids := make([]int64, len(urls))
+	taken := false
+	for i, u := range urls {
+		createdAt := u.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+
+		err := stmt.QueryRowContext(ctx, u.URL, u.Alias, u.RedirectCode, nullTime(u.ExpiresAt), createdAt, nullID(u.OwnerID), urlnorm.Hash(u.URL)).Scan(&ids[i])
+		if errors.Is(err, sql.ErrNoRows) {
+			taken = true
+			continue
+		}
+		if err != nil {
+			if isOwnerMissing(err) {
+				return nil, storage.ErrUserNotFound
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if taken && allOrNothing {
+		return ids, storage.ErrURLExists
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// GetURL retrieves a URL by its alias
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	const op = "storage.postgres.GetURL"
+
+	res, err := scanURL(s.db.QueryRowContext(ctx, "SELECT "+urlColumns+" FROM url WHERE alias = $1", alias))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// DeleteURL deletes a URL by its alias
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	const op = "storage.postgres.DeleteURL"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM url WHERE alias = $1", alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// ListURLs returns a page of links and the number of links matching params
+func (s *Storage) ListURLs(ctx context.Context, params storage.ListParams) ([]storage.URL, int64, error) {
+	const op = "storage.postgres.ListURLs"
+
+	var (
+		conds []string
+		args  []any
+	)
+	if params.Query != "" {
+		args = append(args, "%"+escapeLike(strings.ToLower(params.Query))+"%")
+		conds = append(conds, fmt.Sprintf("(lower(alias) LIKE $%d OR lower(url) LIKE $%d)", len(args), len(args)))
+	}
+	if params.OwnerID != 0 {
+		args = append(args, params.OwnerID)
+		conds = append(conds, fmt.Sprintf("owner_id = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int64
+	if err := s.db.QueryRowContext(ctx, "SELECT count(*) FROM url"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("%s: count: %w", op, err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM url%s ORDER BY %s LIMIT $%d OFFSET $%d",
+		urlColumns, where, orderBy(params), len(args)+1, len(args)+2)
+	rows, err := s.db.QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	urls := make([]storage.URL, 0, params.Limit)
+	for rows.Next() {
+		u, err := scanURL(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, total, nil
+}
+
+// UpdateURL changes the target url of the alias
+func (s *Storage) UpdateURL(ctx context.Context, alias, newURL string) error {
+	const op = "storage.postgres.UpdateURL"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE url SET url = $1, url_hash = $2 WHERE alias = $3", newURL, urlnorm.Hash(newURL), alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// ReplaceURL overwrites every field of the link with the same alias.
+// The link keeps its id, so its clicks stay with it.
+func (s *Storage) ReplaceURL(ctx context.Context, u storage.URL) (int64, error) {
+	const op = "storage.postgres.ReplaceURL"
+
+	createdAt := u.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE url SET url = $1, redirect_code = $2, expires_at = $3, created_at = $4, owner_id = $5, url_hash = $6
+		WHERE alias = $7 RETURNING id`,
+		u.URL, u.RedirectCode, nullTime(u.ExpiresAt), createdAt, nullID(u.OwnerID), urlnorm.Hash(u.URL), u.Alias,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrURLNotFound
+		}
+		if isOwnerMissing(err) {
+			return 0, storage.ErrUserNotFound
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetURLByTarget returns the oldest link of the owner to the same url
+// after normalization, links that expire are skipped
+func (s *Storage) GetURLByTarget(ctx context.Context, ownerID int64, target string) (storage.URL, error) {
+	const op = "storage.postgres.GetURLByTarget"
+
+	res, err := scanURL(s.db.QueryRowContext(ctx,
+		"SELECT "+urlColumns+" FROM url WHERE owner_id IS NOT DISTINCT FROM $1 AND url_hash = $2 AND expires_at IS NULL ORDER BY id LIMIT 1",
+		nullID(ownerID), urlnorm.Hash(target),
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// DeleteExpired deletes links that expired before now and returns how many were removed
+func (s *Storage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	const op = "storage.postgres.DeleteExpired"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM url WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// ArchiveExpired moves links that expired before now to url_archive
+// and returns how many were moved
+func (s *Storage) ArchiveExpired(ctx context.Context, now time.Time) (int64, error) {
+	const op = "storage.postgres.ArchiveExpired"
+
+	res, err := s.db.ExecContext(ctx, `
+		WITH moved AS (
+			DELETE FROM url WHERE expires_at <= $1
+			RETURNING id, alias, url, redirect_code, expires_at
+		)
+		INSERT INTO url_archive (url_id, alias, url, redirect_code, expires_at, archived_at)
+		SELECT id, alias, url, redirect_code, expires_at, $1 FROM moved`,
+		now,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// SaveClicks stores a batch of clicks in one transaction.
+// Clicks on links deleted before the batch is written are dropped.
+func (s *Storage) SaveClicks(ctx context.Context, clicks []storage.Click) error {
+	const op = "storage.postgres.SaveClicks"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// переходы по ссылкам, удалённым до записи, отбрасываются, иначе
+	// внешний ключ на url отклонил бы всю пачку
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO clicks (url_id, clicked_at, referrer, user_agent, remote_addr, request_id)
+		SELECT $1::bigint, $2::timestamptz, $3::text, $4::text, $5::text, $6::text
+		WHERE EXISTS (SELECT 1 FROM url WHERE id = $1)`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	for _, c := range clicks {
+		_, err := stmt.ExecContext(ctx, c.URLID, c.ClickedAt, c.Referrer, c.UserAgent, c.RemoteAddr, c.RequestID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ClickStats returns total clicks on the link and per-day counts since the given moment
+func (s *Storage) ClickStats(ctx context.Context, alias string, since time.Time) (storage.ClickStats, error) {
+	const op = "storage.postgres.ClickStats"
+
+	var urlID int64
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM url WHERE alias = $1", alias).Scan(&urlID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ClickStats{}, storage.ErrURLNotFound
+		}
+		return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var stats storage.ClickStats
+	err = s.db.QueryRowContext(ctx, "SELECT count(*) FROM clicks WHERE url_id = $1", urlID).Scan(&stats.Total)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT to_char(clicked_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, count(*) FROM clicks
+		WHERE url_id = $1 AND clicked_at >= $2
+		GROUP BY day ORDER BY day`,
+		urlID, since,
+	)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count daily clicks: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d storage.DailyClicks
+		if err := rows.Scan(&d.Day, &d.Count); err != nil {
+			return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+		}
+		stats.Daily = append(stats.Daily, d)
+	}
+	if err := rows.Err(); err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+// urlColumns are read by scanURL, in this order
+const urlColumns = "id, alias, url, redirect_code, expires_at, created_at, owner_id"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanURL(row rowScanner) (storage.URL, error) {
+	var (
+		u         storage.URL
+		expiresAt sql.NullTime
+		createdAt sql.NullTime
+		ownerID   sql.NullInt64
+	)
+	if err := row.Scan(&u.ID, &u.Alias, &u.URL, &u.RedirectCode, &expiresAt, &createdAt, &ownerID); err != nil {
+		return storage.URL{}, err
+	}
+	u.ExpiresAt = expiresAt.Time
+	u.CreatedAt = createdAt.Time
+	u.OwnerID = ownerID.Int64
+
+	return u, nil
+}
+
+// orderBy builds ORDER BY from a whitelisted sort key, id breaks ties
+func orderBy(params storage.ListParams) string {
+	column := "id"
+	switch params.SortBy {
+	case storage.SortByAlias, storage.SortByURL, storage.SortByCreatedAt:
+		column = params.SortBy
+	}
+
+	dir := "ASC"
+	if params.Desc {
+		dir = "DESC"
+	}
+
+	if column == "id" {
+		return "id " + dir
+	}
+	// links without created_at go last in both directions
+	return column + " " + dir + " NULLS LAST, id " + dir
+}
+
+// escapeLike escapes LIKE wildcards so they match literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// nullID stores zero id as NULL
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// nullTime stores zero time as NULL
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener/internal/storage"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const userColumns = "id, name, role, created_at"
+
+// SaveUser creates a user and returns its id
+func (s *Storage) SaveUser(ctx context.Context, u storage.User) (int64, error) {
+	const op = "storage.postgres.SaveUser"
+
+	createdAt := u.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	if u.Role == "" {
+		u.Role = storage.RoleViewer
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		"INSERT INTO users (name, role, created_at) VALUES ($1, $2, $3) RETURNING id",
+		u.Name, u.Role, createdAt,
+	).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return 0, storage.ErrUserExists
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetUser finds a user by id
+func (s *Storage) GetUser(ctx context.Context, id int64) (storage.User, error) {
+	const op = "storage.postgres.GetUser"
+
+	u, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.User{}, storage.ErrUserNotFound
+		}
+		return storage.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, nil
+}
+
+// GetUserByName finds a user by name
+func (s *Storage) GetUserByName(ctx context.Context, name string) (storage.User, error) {
+	const op = "storage.postgres.GetUserByName"
+
+	u, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE name = $1", name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.User{}, storage.ErrUserNotFound
+		}
+		return storage.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, nil
+}
+
+// SetUserRole changes the role of the user
+func (s *Storage) SetUserRole(ctx context.Context, id int64, role string) error {
+	const op = "storage.postgres.SetUserRole"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET role = $1 WHERE id = $2", role, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ListUsers returns all users ordered by id
+func (s *Storage) ListUsers(ctx context.Context) ([]storage.User, error) {
+	const op = "storage.postgres.ListUsers"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []storage.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+func scanUser(row rowScanner) (storage.User, error) {
+	var (
+		u         storage.User
+		createdAt sql.NullTime
+	)
+	if err := row.Scan(&u.ID, &u.Name, &u.Role, &createdAt); err != nil {
+		return storage.User{}, err
+	}
+	u.CreatedAt = createdAt.Time
+
+	return u, nil
+}
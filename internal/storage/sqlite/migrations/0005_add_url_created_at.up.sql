@@ -0,0 +1,4 @@
+-- sqlite can't add a column with a non-constant default,
+-- so created_at is set by the application and stays NULL for older links
+ALTER TABLE url ADD COLUMN created_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_url_created_at ON url (created_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_url_owner_id;
+ALTER TABLE url DROP COLUMN owner_id;
+ALTER TABLE api_keys DROP COLUMN user_id;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	is_admin INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL);
+ALTER TABLE api_keys ADD COLUMN user_id INTEGER;
+-- links saved before owners were recorded stay without one, only admins manage them
+ALTER TABLE url ADD COLUMN owner_id INTEGER;
+CREATE INDEX IF NOT EXISTS idx_url_owner_id ON url (owner_id);
+-- keys created before users existed had access to every link, keep it by giving them an admin
+INSERT INTO users (name, is_admin, created_at)
+	SELECT 'admin', 1, datetime('now') WHERE EXISTS (SELECT 1 FROM api_keys);
+UPDATE api_keys SET user_id = (SELECT id FROM users WHERE name = 'admin') WHERE user_id IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN is_admin INTEGER NOT NULL DEFAULT 0;
+UPDATE users SET is_admin = (role = 'admin');
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_url_owner_hash;
+ALTER TABLE url DROP COLUMN url_hash;
@@ -0,0 +1,4 @@
+-- hash of the normalized url, so links to the same target are found by index;
+-- NULL for links saved before it was recorded, they are not reused
+ALTER TABLE url ADD COLUMN url_hash TEXT;
+CREATE INDEX IF NOT EXISTS idx_url_owner_hash ON url (owner_id, url_hash);
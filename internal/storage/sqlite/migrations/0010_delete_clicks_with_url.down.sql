@@ -0,0 +1 @@
+DROP TRIGGER IF EXISTS trg_url_delete_clicks;
@@ -0,0 +1,8 @@
+-- clicks go away together with their link. sqlite runs without foreign keys,
+-- so a trigger does what ON DELETE CASCADE does on postgres; it also fires
+-- when expired links are deleted or moved to url_archive
+DELETE FROM clicks WHERE url_id NOT IN (SELECT id FROM url);
+CREATE TRIGGER IF NOT EXISTS trg_url_delete_clicks AFTER DELETE ON url
+BEGIN
+	DELETE FROM clicks WHERE url_id = OLD.id;
+END;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_url_owner_update;
+DROP TRIGGER IF EXISTS trg_url_owner_insert;
@@ -0,0 +1,13 @@
+-- owner_id must name an existing user, as the foreign key on postgres
+-- requires; sqlite runs without foreign keys, so triggers check it.
+-- Links saved earlier are not checked.
+CREATE TRIGGER IF NOT EXISTS trg_url_owner_insert BEFORE INSERT ON url
+WHEN NEW.owner_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM users WHERE id = NEW.owner_id)
+BEGIN
+	SELECT RAISE(ABORT, 'url.owner_id: no such user');
+END;
+CREATE TRIGGER IF NOT EXISTS trg_url_owner_update BEFORE UPDATE OF owner_id ON url
+WHEN NEW.owner_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM users WHERE id = NEW.owner_id)
+BEGIN
+	SELECT RAISE(ABORT, 'url.owner_id: no such user');
+END;
@@ -1,57 +1,163 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"embed"
 	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+	"url-shortener/internal/lib/urlnorm"
 	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/migrate"
 
 	"modernc.org/sqlite" // init sqlite driver
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
 type Storage struct {
 	db *sql.DB
 }
 
-func New(storagePath string) (*Storage, error) {
-	const op = "storage.sqlite.New"
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Open opens the database without touching its schema
+func Open(storagePath string) (*Storage, error) {
+	const op = "storage.sqlite.Open"
 	db, err := sql.Open("sqlite", storagePath)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmt, err := db.Prepare(`
-		CREATE TABLE IF NOT EXISTS url (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			alias TEXT NOT NULL UNIQUE,
-			url TEXT NOT NULL);
-		CREATE INDEX IF NOT EXISTS idx_alias ON url (alias);
-	`)
+	return &Storage{db: db}, nil
+}
+
+// New opens the database and applies pending migrations
+func New(storagePath string) (*Storage, error) {
+	const op = "storage.sqlite.New"
+	s, err := Open(storagePath)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = stmt.Exec()
+	m, err := s.Migrator()
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	if _, err := m.Up(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// Close closes the database
+func (s *Storage) Close() error {
+	const op = "storage.sqlite.Close"
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Ping checks that the database answers
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.sqlite.Ping"
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the latest applied migration
+func (s *Storage) SchemaVersion(ctx context.Context) (int, error) {
+	const op = "storage.sqlite.SchemaVersion"
+	version, err := migrate.Current(ctx, s.db)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// Migrator returns migrator over the embedded sqlite migrations
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.New(s.db, fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.adoptLegacySchema(m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// adoptLegacySchema records 0001 and 0002 as applied for databases created
+// before migrations: their url table already has redirect_code,
+// and 0002 would fail to add it again.
+func (s *Storage) adoptLegacySchema(m *migrate.Migrator) error {
+	ok, err := s.hasColumn("url", "redirect_code")
+	if err != nil {
+		return fmt.Errorf("detect legacy schema: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+
+	if _, err := m.Baseline(2); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// hasColumn reports whether the table exists and has the column
+func (s *Storage) hasColumn(table, column string) (bool, error) {
+	var n int
+	err := s.db.QueryRow("SELECT count(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
 }
 
 // функция для сохранения урла в базу данных
-func (s *Storage) SaveURL(urlToSave, alias string) (int64, error) {
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
 	const op = "storage.sqlite.SaveURL"
-	stmt, err := s.db.Prepare("INSERT INTO url (url, alias) VALUES (?, ?)")
+	stmt, err := s.db.PrepareContext(ctx, "INSERT INTO url (url, alias, redirect_code, expires_at, created_at, owner_id, url_hash) VALUES (?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
-	res, err := stmt.Exec(urlToSave, alias)
+	createdAt := u.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	res, err := stmt.ExecContext(ctx, u.URL, u.Alias, u.RedirectCode, nullTime(u.ExpiresAt), formatTime(createdAt), nullID(u.OwnerID), urlnorm.Hash(u.URL))
 	if err != nil {
-		// Код ошибки 19 — SQLITE_CONSTRAINT_UNIQUE в SQLite (нарушение уникального ограничения)
-		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == 19 {
+		// драйвер возвращает расширенный код 2067 — SQLITE_CONSTRAINT_UNIQUE (нарушение уникального ограничения),
+		// а не основной код 19 (SQLITE_CONSTRAINT)
+		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
 			return 0, storage.ErrURLExists
 		}
+		if isOwnerMissing(err) {
+			return 0, storage.ErrUserNotFound
+		}
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 	id, err := res.LastInsertId()
@@ -61,35 +167,97 @@ func (s *Storage) SaveURL(urlToSave, alias string) (int64, error) {
 	return id, nil
 }
 
+// SaveURLs saves links in one transaction and returns their ids in order,
+// zero for links whose alias is taken. With allOrNothing nothing is saved
+// if any alias is taken.
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL, allOrNothing bool) ([]int64, error) {
+	const op = "storage.sqlite.SaveURLs"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// занятый алиас не прерывает транзакцию, строка просто не вставляется
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO url (url, alias, redirect_code, expires_at, created_at, owner_id, url_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (alias) DO NOTHING`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	ids := make([]int64, len(urls))
+	taken := false
+	for i, u := range urls {
+		createdAt := u.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+
+		res, err := stmt.ExecContext(ctx, u.URL, u.Alias, u.RedirectCode, nullTime(u.ExpiresAt), formatTime(createdAt), nullID(u.OwnerID), urlnorm.Hash(u.URL))
+		if err != nil {
+			if isOwnerMissing(err) {
+				return nil, storage.ErrUserNotFound
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if n == 0 {
+			taken = true
+			continue
+		}
+		if ids[i], err = res.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+		}
+	}
+
+	if taken && allOrNothing {
+		return ids, storage.ErrURLExists
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
 // GetURL retrieves a URL by its alias
-func (s *Storage) GetURL(alias string) (string, error) {
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
 	const op = "storage.sqlite.GetURL"
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+	stmt, err := s.db.PrepareContext(ctx, "SELECT "+urlColumns+" FROM url WHERE alias = ?")
 	if err != nil {
-		return "", fmt.Errorf("%s: prepare statement: %w", op, err)
+		return storage.URL{}, fmt.Errorf("%s: prepare statement: %w", op, err)
 	}
+	defer stmt.Close()
 
-	var resUrl string
-	err = stmt.QueryRow(alias).Scan(&resUrl)
+	res, err := scanURL(stmt.QueryRowContext(ctx, alias))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", storage.ErrURLNotFound
+			return storage.URL{}, storage.ErrURLNotFound
 		}
-		return "", fmt.Errorf("%s: execute statement: %w", op, err)
+		return storage.URL{}, fmt.Errorf("%s: execute statement: %w", op, err)
 	}
 
-	return resUrl, nil
+	return res, nil
 }
 
 // DeleteURL deletes a URL by its alias
-func (s *Storage) DeleteURL(alias string) error {
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
 	const op = "storage.sqlite.DeleteURL"
-	stmt, err := s.db.Prepare("DELETE FROM url WHERE alias = ?")
+	stmt, err := s.db.PrepareContext(ctx, "DELETE FROM url WHERE alias = ?")
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer stmt.Close()
 
-	res, err := stmt.Exec(alias)
+	res, err := stmt.ExecContext(ctx, alias)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -105,3 +273,339 @@ func (s *Storage) DeleteURL(alias string) error {
 
 	return nil
 }
+
+// ListURLs returns a page of links and the number of links matching params
+func (s *Storage) ListURLs(ctx context.Context, params storage.ListParams) ([]storage.URL, int64, error) {
+	const op = "storage.sqlite.ListURLs"
+
+	var (
+		conds []string
+		args  []any
+	)
+	if params.Query != "" {
+		conds = append(conds, `(lower(alias) LIKE ? ESCAPE '\' OR lower(url) LIKE ? ESCAPE '\')`)
+		pattern := "%" + escapeLike(strings.ToLower(params.Query)) + "%"
+		args = append(args, pattern, pattern)
+	}
+	if params.OwnerID != 0 {
+		conds = append(conds, "owner_id = ?")
+		args = append(args, params.OwnerID)
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int64
+	if err := s.db.QueryRowContext(ctx, "SELECT count(*) FROM url"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("%s: count: %w", op, err)
+	}
+
+	query := "SELECT " + urlColumns + " FROM url" + where +
+		" ORDER BY " + orderBy(params) + " LIMIT ? OFFSET ?"
+	rows, err := s.db.QueryContext(ctx, query, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	urls := make([]storage.URL, 0, params.Limit)
+	for rows.Next() {
+		u, err := scanURL(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		urls = append(urls, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return urls, total, nil
+}
+
+// UpdateURL changes the target url of the alias
+func (s *Storage) UpdateURL(ctx context.Context, alias, newURL string) error {
+	const op = "storage.sqlite.UpdateURL"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE url SET url = ?, url_hash = ? WHERE alias = ?", newURL, urlnorm.Hash(newURL), alias)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// ReplaceURL overwrites every field of the link with the same alias.
+// The link keeps its id, so its clicks stay with it.
+func (s *Storage) ReplaceURL(ctx context.Context, u storage.URL) (int64, error) {
+	const op = "storage.sqlite.ReplaceURL"
+
+	createdAt := u.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE url SET url = ?, redirect_code = ?, expires_at = ?, created_at = ?, owner_id = ?, url_hash = ?
+		WHERE alias = ? RETURNING id`,
+		u.URL, u.RedirectCode, nullTime(u.ExpiresAt), formatTime(createdAt), nullID(u.OwnerID), urlnorm.Hash(u.URL), u.Alias,
+	).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, storage.ErrURLNotFound
+		}
+		if isOwnerMissing(err) {
+			return 0, storage.ErrUserNotFound
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// isOwnerMissing reports whether the owner check of migration 0011 rejected
+// the link: RAISE in a trigger returns SQLITE_CONSTRAINT_TRIGGER, and the
+// triggers of url raise only for an unknown owner_id
+func isOwnerMissing(err error) bool {
+	sqliteErr, ok := err.(*sqlite.Error)
+	return ok && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_TRIGGER
+}
+
+// GetURLByTarget returns the oldest link of the owner to the same url
+// after normalization, links that expire are skipped
+func (s *Storage) GetURLByTarget(ctx context.Context, ownerID int64, target string) (storage.URL, error) {
+	const op = "storage.sqlite.GetURLByTarget"
+
+	res, err := scanURL(s.db.QueryRowContext(ctx,
+		"SELECT "+urlColumns+" FROM url WHERE owner_id IS ? AND url_hash = ? AND expires_at IS NULL ORDER BY id LIMIT 1",
+		nullID(ownerID), urlnorm.Hash(target),
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.URL{}, storage.ErrURLNotFound
+		}
+		return storage.URL{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res, nil
+}
+
+// DeleteExpired deletes links that expired before now and returns how many were removed
+func (s *Storage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	const op = "storage.sqlite.DeleteExpired"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM url WHERE expires_at <= ?", formatTime(now))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// ArchiveExpired moves links that expired before now to url_archive
+// and returns how many were moved
+func (s *Storage) ArchiveExpired(ctx context.Context, now time.Time) (int64, error) {
+	const op = "storage.sqlite.ArchiveExpired"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	cutoff := formatTime(now)
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO url_archive (url_id, alias, url, redirect_code, expires_at, archived_at)
+		SELECT id, alias, url, redirect_code, expires_at, ? FROM url WHERE expires_at <= ?`,
+		cutoff, cutoff,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%s: copy to archive: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM url WHERE expires_at <= ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// SaveClicks stores a batch of clicks in one transaction.
+// Clicks on links deleted before the batch is written are dropped.
+func (s *Storage) SaveClicks(ctx context.Context, clicks []storage.Click) error {
+	const op = "storage.sqlite.SaveClicks"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// переходы по ссылкам, удалённым до записи, отбрасываются
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO clicks (url_id, clicked_at, referrer, user_agent, remote_addr, request_id)
+		SELECT ?, ?, ?, ?, ?, ?
+		WHERE EXISTS (SELECT 1 FROM url WHERE id = ?)`)
+	if err != nil {
+		return fmt.Errorf("%s: prepare statement: %w", op, err)
+	}
+	defer stmt.Close()
+
+	for _, c := range clicks {
+		_, err := stmt.ExecContext(ctx, c.URLID, formatTime(c.ClickedAt), c.Referrer, c.UserAgent, c.RemoteAddr, c.RequestID, c.URLID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ClickStats returns total clicks on the link and per-day counts since the given moment
+func (s *Storage) ClickStats(ctx context.Context, alias string, since time.Time) (storage.ClickStats, error) {
+	const op = "storage.sqlite.ClickStats"
+
+	var urlID int64
+	err := s.db.QueryRowContext(ctx, "SELECT id FROM url WHERE alias = ?", alias).Scan(&urlID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return storage.ClickStats{}, storage.ErrURLNotFound
+		}
+		return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var stats storage.ClickStats
+	err = s.db.QueryRowContext(ctx, "SELECT count(*) FROM clicks WHERE url_id = ?", urlID).Scan(&stats.Total)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count clicks: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT date(clicked_at) AS day, count(*) FROM clicks
+		WHERE url_id = ? AND clicked_at >= ?
+		GROUP BY day ORDER BY day`,
+		urlID, formatTime(since),
+	)
+	if err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: count daily clicks: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d storage.DailyClicks
+		if err := rows.Scan(&d.Day, &d.Count); err != nil {
+			return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+		}
+		stats.Daily = append(stats.Daily, d)
+	}
+	if err := rows.Err(); err != nil {
+		return storage.ClickStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return stats, nil
+}
+
+// urlColumns are read by scanURL, in this order
+const urlColumns = "id, alias, url, redirect_code, expires_at, created_at, owner_id"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanURL(row rowScanner) (storage.URL, error) {
+	var (
+		u         storage.URL
+		expiresAt sql.NullTime
+		createdAt sql.NullTime
+		ownerID   sql.NullInt64
+	)
+	if err := row.Scan(&u.ID, &u.Alias, &u.URL, &u.RedirectCode, &expiresAt, &createdAt, &ownerID); err != nil {
+		return storage.URL{}, err
+	}
+	u.ExpiresAt = expiresAt.Time
+	u.CreatedAt = createdAt.Time
+	u.OwnerID = ownerID.Int64
+
+	return u, nil
+}
+
+// orderBy builds ORDER BY from a whitelisted sort key, id breaks ties
+func orderBy(params storage.ListParams) string {
+	column := "id"
+	switch params.SortBy {
+	case storage.SortByAlias, storage.SortByURL, storage.SortByCreatedAt:
+		column = params.SortBy
+	}
+
+	dir := "ASC"
+	if params.Desc {
+		dir = "DESC"
+	}
+
+	if column == "id" {
+		return "id " + dir
+	}
+	// links without created_at go last in both directions
+	return column + " " + dir + " NULLS LAST, id " + dir
+}
+
+// escapeLike escapes LIKE wildcards so they match literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// timeLayout is how times are written to sqlite: UTC in the datetime('now') format,
+// so stored values compare correctly as strings
+const timeLayout = "2006-01-02 15:04:05"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// nullID stores zero id as NULL
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// nullTime stores zero time as NULL
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return formatTime(t)
+}
@@ -0,0 +1,596 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"url-shortener/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	id, err := s.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com", RedirectCode: 301})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	assert.ErrorIs(t, err, storage.ErrURLExists)
+
+	got, err := s.GetURL(ctx, "google")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), got.CreatedAt, time.Minute)
+	got.CreatedAt = time.Time{}
+	assert.Equal(t, storage.URL{ID: id, Alias: "google", URL: "https://www.google.com", RedirectCode: 301}, got)
+
+	require.NoError(t, s.UpdateURL(ctx, "google", "https://www.google.ru"))
+	assert.ErrorIs(t, s.UpdateURL(ctx, "missing", "https://example.com"), storage.ErrURLNotFound)
+	got, err = s.GetURL(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.google.ru", got.URL)
+
+	require.NoError(t, s.DeleteURL(ctx, "google"))
+	assert.ErrorIs(t, s.DeleteURL(ctx, "google"), storage.ErrURLNotFound)
+
+	_, err = s.GetURL(ctx, "google")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+// databases created before migrations existed only have the url table
+func TestNewUpgradesLegacyDatabase(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE url (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			alias TEXT NOT NULL UNIQUE,
+			url TEXT NOT NULL);
+		CREATE INDEX idx_alias ON url (alias);
+		INSERT INTO url (alias, url) VALUES ('google', 'https://www.google.com');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	s, err := New(path)
+	require.NoError(t, err)
+
+	got, err := s.GetURL(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.google.com", got.URL)
+	assert.Zero(t, got.RedirectCode)
+
+	m, err := s.Migrator()
+	require.NoError(t, err)
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	for _, st := range statuses {
+		assert.True(t, st.Applied, "migration %04d_%s is not applied", st.Version, st.Name)
+	}
+}
+
+func TestNewAdoptsSchemaWithRedirectCode(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	// схема, которую создавали версии до миграций
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE url (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			alias TEXT NOT NULL UNIQUE,
+			url TEXT NOT NULL,
+			redirect_code INTEGER NOT NULL DEFAULT 0);
+		CREATE INDEX idx_alias ON url (alias);
+		INSERT INTO url (alias, url, redirect_code) VALUES ('google', 'https://www.google.com', 301);
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	s, err := New(path)
+	require.NoError(t, err)
+
+	got, err := s.GetURL(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "https://www.google.com", got.URL)
+	assert.Equal(t, 301, got.RedirectCode)
+
+	m, err := s.Migrator()
+	require.NoError(t, err)
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	for _, st := range statuses {
+		assert.True(t, st.Applied, "migration %04d_%s is not applied", st.Version, st.Name)
+	}
+
+	// повторный запуск не должен ломаться
+	require.NoError(t, s.Close())
+	s, err = New(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+}
+
+func TestExpired(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now()
+
+	for _, archive := range []bool{false, true} {
+		s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+		require.NoError(t, err)
+
+		expiresAt := now.Add(-time.Minute).Truncate(time.Second)
+		_, err = s.SaveURL(ctx, storage.URL{Alias: "expired", URL: "https://example.com", ExpiresAt: expiresAt})
+		require.NoError(t, err)
+		_, err = s.SaveURL(ctx, storage.URL{Alias: "alive", URL: "https://example.com", ExpiresAt: now.Add(time.Hour)})
+		require.NoError(t, err)
+		_, err = s.SaveURL(ctx, storage.URL{Alias: "forever", URL: "https://example.com"})
+		require.NoError(t, err)
+
+		got, err := s.GetURL(ctx, "expired")
+		require.NoError(t, err)
+		assert.True(t, got.ExpiresAt.Equal(expiresAt), "expires_at %v, want %v", got.ExpiresAt, expiresAt)
+		assert.True(t, got.Expired(now))
+
+		got, err = s.GetURL(ctx, "forever")
+		require.NoError(t, err)
+		assert.True(t, got.ExpiresAt.IsZero())
+
+		var n int64
+		if archive {
+			n, err = s.ArchiveExpired(ctx, now)
+		} else {
+			n, err = s.DeleteExpired(ctx, now)
+		}
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		_, err = s.GetURL(ctx, "expired")
+		assert.ErrorIs(t, err, storage.ErrURLNotFound)
+		_, err = s.GetURL(ctx, "alive")
+		assert.NoError(t, err)
+
+		var archived int
+		require.NoError(t, s.db.QueryRow("SELECT count(*) FROM url_archive WHERE alias = 'expired'").Scan(&archived))
+		if archive {
+			assert.Equal(t, 1, archived)
+		} else {
+			assert.Zero(t, archived)
+		}
+	}
+}
+
+func TestClickStats(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	id, err := s.SaveURL(ctx, storage.URL{Alias: "google", URL: "https://www.google.com"})
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	require.NoError(t, s.SaveClicks(ctx, []storage.Click{
+		{URLID: id, ClickedAt: now, Referrer: "https://news.example.com", UserAgent: "test-agent"},
+		{URLID: id, ClickedAt: now},
+		{URLID: id, ClickedAt: now.AddDate(0, 0, -1)},
+		{URLID: id, ClickedAt: now.AddDate(0, 0, -100)},
+	}))
+
+	stats, err := s.ClickStats(ctx, "google", now.AddDate(0, 0, -7))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stats.Total)
+	assert.Equal(t, []storage.DailyClicks{
+		{Day: now.AddDate(0, 0, -1).Format("2006-01-02"), Count: 1},
+		{Day: now.Format("2006-01-02"), Count: 2},
+	}, stats.Daily)
+
+	_, err = s.ClickStats(ctx, "missing", now)
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestClicksDeletedWithURL(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	ids := make(map[string]int64)
+	for _, u := range []storage.URL{
+		{Alias: "deleted", URL: "https://example.com"},
+		{Alias: "expired", URL: "https://example.com", ExpiresAt: now.Add(-time.Hour)},
+		{Alias: "alive", URL: "https://example.com"},
+	} {
+		id, err := s.SaveURL(ctx, u)
+		require.NoError(t, err)
+		ids[u.Alias] = id
+	}
+
+	require.NoError(t, s.SaveClicks(ctx, []storage.Click{
+		{URLID: ids["deleted"], ClickedAt: now},
+		{URLID: ids["expired"], ClickedAt: now},
+		{URLID: ids["alive"], ClickedAt: now},
+		// ссылка удалена до записи пачки
+		{URLID: 100, ClickedAt: now},
+	}))
+
+	clicks := func() map[int64]int {
+		rows, err := s.db.Query("SELECT url_id, count(*) FROM clicks GROUP BY url_id")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		got := make(map[int64]int)
+		for rows.Next() {
+			var id int64
+			var n int
+			require.NoError(t, rows.Scan(&id, &n))
+			got[id] = n
+		}
+		require.NoError(t, rows.Err())
+		return got
+	}
+	assert.Equal(t, map[int64]int{ids["deleted"]: 1, ids["expired"]: 1, ids["alive"]: 1}, clicks())
+
+	require.NoError(t, s.DeleteURL(ctx, "deleted"))
+	_, err = s.ArchiveExpired(ctx, now)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[int64]int{ids["alive"]: 1}, clicks())
+
+	// миграция убирает переходы, оставшиеся от удалённых раньше ссылок
+	m, err := s.Migrator()
+	require.NoError(t, err)
+	for {
+		version, err := m.Version()
+		require.NoError(t, err)
+		if version < 10 {
+			break
+		}
+		_, err = m.Down()
+		require.NoError(t, err)
+	}
+	_, err = s.db.Exec("INSERT INTO clicks (url_id, clicked_at) VALUES (?, ?)", 100, formatTime(now))
+	require.NoError(t, err)
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	assert.Equal(t, map[int64]int{ids["alive"]: 1}, clicks())
+}
+
+func TestListURLs(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	base := time.Now().Add(-time.Hour)
+	for i, u := range []storage.URL{
+		{Alias: "b_go", URL: "https://go.dev"},
+		{Alias: "a_google", URL: "https://www.google.com"},
+		{Alias: "c_mail", URL: "https://mail.ru/100%"},
+	} {
+		u.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		_, err := s.SaveURL(ctx, u)
+		require.NoError(t, err)
+	}
+
+	aliases := func(urls []storage.URL) []string {
+		res := make([]string, 0, len(urls))
+		for _, u := range urls {
+			res = append(res, u.Alias)
+		}
+		return res
+	}
+
+	cases := []struct {
+		name      string
+		params    storage.ListParams
+		want      []string
+		wantTotal int64
+	}{
+		{name: "By id", params: storage.ListParams{Limit: 10}, want: []string{"b_go", "a_google", "c_mail"}, wantTotal: 3},
+		{name: "By alias", params: storage.ListParams{Limit: 10, SortBy: storage.SortByAlias}, want: []string{"a_google", "b_go", "c_mail"}, wantTotal: 3},
+		{name: "Newest first", params: storage.ListParams{Limit: 10, SortBy: storage.SortByCreatedAt, Desc: true}, want: []string{"c_mail", "a_google", "b_go"}, wantTotal: 3},
+		{name: "Page", params: storage.ListParams{Limit: 1, Offset: 1}, want: []string{"a_google"}, wantTotal: 3},
+		{name: "Query ignores case", params: storage.ListParams{Limit: 10, Query: "GO"}, want: []string{"b_go", "a_google"}, wantTotal: 2},
+		{name: "Wildcards match literally", params: storage.ListParams{Limit: 10, Query: "_"}, want: []string{"b_go", "a_google", "c_mail"}, wantTotal: 3},
+		{name: "Percent", params: storage.ListParams{Limit: 10, Query: "%"}, want: []string{"c_mail"}, wantTotal: 1},
+		{name: "Past the end", params: storage.ListParams{Limit: 10, Offset: 5}, want: []string{}, wantTotal: 3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			urls, total, err := s.ListURLs(ctx, tc.params)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, aliases(urls))
+			assert.Equal(t, tc.wantTotal, total)
+		})
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	id, err := s.SaveAPIKey(ctx, storage.APIKey{Name: "ci", Prefix: "usk_abcdefgh", Hash: "hash", Scopes: []string{"read", "write"}})
+	require.NoError(t, err)
+
+	got, err := s.GetAPIKeyByHash(ctx, "hash")
+	require.NoError(t, err)
+	assert.Equal(t, id, got.ID)
+	assert.Equal(t, []string{"read", "write"}, got.Scopes)
+	assert.False(t, got.Revoked())
+
+	_, err = s.GetAPIKeyByHash(ctx, "other")
+	assert.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	require.NoError(t, s.RevokeAPIKey(ctx, id, time.Now()))
+	assert.ErrorIs(t, s.RevokeAPIKey(ctx, id, time.Now()), storage.ErrAPIKeyNotFound)
+
+	keys, err := s.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, keys[0].Revoked())
+}
+
+func TestUsersAndOwners(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	aliceID, err := s.SaveUser(ctx, storage.User{Name: "alice"})
+	require.NoError(t, err)
+	_, err = s.SaveUser(ctx, storage.User{Name: "alice"})
+	assert.ErrorIs(t, err, storage.ErrUserExists)
+
+	alice, err := s.GetUserByName(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, aliceID, alice.ID)
+	assert.Equal(t, storage.RoleViewer, alice.Role)
+
+	_, err = s.GetUser(ctx, aliceID+1)
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	require.NoError(t, s.SetUserRole(ctx, aliceID, storage.RoleEditor))
+	alice, err = s.GetUser(ctx, aliceID)
+	require.NoError(t, err)
+	assert.Equal(t, storage.RoleEditor, alice.Role)
+	assert.ErrorIs(t, s.SetUserRole(ctx, aliceID+1, storage.RoleAdmin), storage.ErrUserNotFound)
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "alices", URL: "https://alice.example.com", OwnerID: aliceID})
+	require.NoError(t, err)
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "legacy", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	got, err := s.GetURL(ctx, "alices")
+	require.NoError(t, err)
+	assert.Equal(t, aliceID, got.OwnerID)
+
+	urls, total, err := s.ListURLs(ctx, storage.ListParams{Limit: 10, OwnerID: aliceID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "alices", urls[0].Alias)
+}
+
+// keys created before users existed get an admin so they keep working
+func TestUsersMigrationKeepsExistingKeys(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	m, err := s.Migrator()
+	require.NoError(t, err)
+	for {
+		mig, err := m.Down()
+		require.NoError(t, err)
+		if mig.Name == "create_users" {
+			break
+		}
+	}
+
+	_, err = s.db.Exec(`INSERT INTO api_keys (name, prefix, hash, scopes, created_at) VALUES ('old', 'usk_old', 'hash', 'read', datetime('now'))`)
+	require.NoError(t, err)
+
+	_, err = m.Up()
+	require.NoError(t, err)
+
+	key, err := s.GetAPIKeyByHash(ctx, "hash")
+	require.NoError(t, err)
+	user, err := s.GetUser(ctx, key.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", user.Name)
+	assert.True(t, user.IsAdmin())
+}
+
+func TestGetURLByTarget(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	alice, err := s.SaveUser(ctx, storage.User{Name: "alice"})
+	require.NoError(t, err)
+	bob, err := s.SaveUser(ctx, storage.User{Name: "bob"})
+	require.NoError(t, err)
+
+	for _, u := range []storage.URL{
+		{Alias: "first", URL: "https://Example.com:443/?b=2&a=1", OwnerID: alice},
+		{Alias: "second", URL: "https://example.com/?a=1&b=2", OwnerID: alice},
+		{Alias: "expiring", URL: "https://go.dev", OwnerID: alice, ExpiresAt: time.Now().Add(time.Hour)},
+		{Alias: "bobs", URL: "https://example.com/?a=1&b=2", OwnerID: bob},
+		{Alias: "legacy", URL: "https://example.com/?a=1&b=2"},
+	} {
+		_, err := s.SaveURL(ctx, u)
+		require.NoError(t, err)
+	}
+
+	got, err := s.GetURLByTarget(ctx, alice, "https://EXAMPLE.com?a=1&b=2")
+	require.NoError(t, err)
+	assert.Equal(t, "first", got.Alias)
+
+	got, err = s.GetURLByTarget(ctx, bob, "https://example.com/?b=2&a=1")
+	require.NoError(t, err)
+	assert.Equal(t, "bobs", got.Alias)
+
+	got, err = s.GetURLByTarget(ctx, 0, "https://example.com/?b=2&a=1")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy", got.Alias)
+
+	_, err = s.GetURLByTarget(ctx, alice, "https://go.dev")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// после смены адреса ссылка находится по новому
+	require.NoError(t, s.UpdateURL(ctx, "expiring", "https://example.org"))
+	require.NoError(t, s.UpdateURL(ctx, "second", "https://example.org"))
+	_, err = s.GetURLByTarget(ctx, alice, "http://example.org:80")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+	got, err = s.GetURLByTarget(ctx, alice, "https://example.org")
+	require.NoError(t, err)
+	assert.Equal(t, "second", got.Alias)
+}
+
+func TestSaveURLs(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "taken", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	batch := []storage.URL{
+		{Alias: "one", URL: "https://example.com/1"},
+		{Alias: "taken", URL: "https://example.com/2"},
+		{Alias: "two", URL: "https://example.com/3", RedirectCode: 301},
+		{Alias: "one", URL: "https://example.com/4"},
+	}
+
+	// все или ничего: ничего не сохранено, нули отмечают занятые алиасы
+	ids, err := s.SaveURLs(ctx, batch, true)
+	assert.ErrorIs(t, err, storage.ErrURLExists)
+	require.Len(t, ids, 4)
+	assert.NotZero(t, ids[0])
+	assert.Zero(t, ids[1])
+	assert.Zero(t, ids[3])
+	_, err = s.GetURL(ctx, "one")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	// по возможности: сохраняется всё, что можно
+	ids, err = s.SaveURLs(ctx, batch, false)
+	require.NoError(t, err)
+	require.Len(t, ids, 4)
+	assert.Zero(t, ids[1])
+	assert.Zero(t, ids[3])
+
+	got, err := s.GetURL(ctx, "one")
+	require.NoError(t, err)
+	assert.Equal(t, ids[0], got.ID)
+	assert.Equal(t, "https://example.com/1", got.URL)
+
+	got, err = s.GetURL(ctx, "two")
+	require.NoError(t, err)
+	assert.Equal(t, ids[2], got.ID)
+	assert.Equal(t, 301, got.RedirectCode)
+	assert.WithinDuration(t, time.Now(), got.CreatedAt, time.Minute)
+}
+
+func TestPingAndSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Ping(ctx))
+
+	m, err := s.Migrator()
+	require.NoError(t, err)
+	want, err := m.Version()
+	require.NoError(t, err)
+
+	version, err := s.SchemaVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, want, version)
+	assert.Positive(t, version)
+
+	require.NoError(t, s.Close())
+	assert.Error(t, s.Ping(ctx))
+}
+
+func TestReplaceURL(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	id, err := s.SaveURL(ctx, storage.URL{Alias: "link", URL: "https://old.example", RedirectCode: 301})
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	createdAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	got, err := s.ReplaceURL(ctx, storage.URL{Alias: "link", URL: "https://new.example", ExpiresAt: expiresAt, CreatedAt: createdAt})
+	require.NoError(t, err)
+	assert.Equal(t, id, got)
+
+	u, err := s.GetURL(ctx, "link")
+	require.NoError(t, err)
+	assert.Equal(t, id, u.ID)
+	assert.Equal(t, "https://new.example", u.URL)
+	assert.Zero(t, u.RedirectCode)
+	assert.True(t, expiresAt.Equal(u.ExpiresAt))
+	assert.True(t, createdAt.Equal(u.CreatedAt))
+
+	_, err = s.ReplaceURL(ctx, storage.URL{Alias: "missing", URL: "https://new.example"})
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestUnknownOwner(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	require.NoError(t, err)
+
+	ownerID, err := s.SaveUser(ctx, storage.User{Name: "alice", Role: storage.RoleEditor})
+	require.NoError(t, err)
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "orphan", URL: "https://example.com", OwnerID: ownerID + 100})
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	_, err = s.SaveURLs(ctx, []storage.URL{
+		{Alias: "one", URL: "https://example.com/1", OwnerID: ownerID},
+		{Alias: "two", URL: "https://example.com/2", OwnerID: ownerID + 100},
+	}, false)
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+	_, err = s.GetURL(ctx, "one")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound, "the batch is rolled back")
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "owned", URL: "https://example.com", OwnerID: ownerID})
+	require.NoError(t, err)
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "ownerless", URL: "https://example.com"})
+	require.NoError(t, err)
+
+	_, err = s.ReplaceURL(ctx, storage.URL{Alias: "owned", URL: "https://example.org", OwnerID: ownerID + 100})
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	u, err := s.GetURL(ctx, "owned")
+	require.NoError(t, err)
+	assert.Equal(t, ownerID, u.OwnerID)
+}
@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener/internal/storage"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const userColumns = "id, name, role, created_at"
+
+// SaveUser creates a user and returns its id
+func (s *Storage) SaveUser(ctx context.Context, u storage.User) (int64, error) {
+	const op = "storage.sqlite.SaveUser"
+
+	createdAt := u.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	if u.Role == "" {
+		u.Role = storage.RoleViewer
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"INSERT INTO users (name, role, created_at) VALUES (?, ?, ?)",
+		u.Name, u.Role, formatTime(createdAt),
+	)
+	if err != nil {
+		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+			return 0, storage.ErrUserExists
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get last insert id: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetUser finds a user by id
+func (s *Storage) GetUser(ctx context.Context, id int64) (storage.User, error) {
+	const op = "storage.sqlite.GetUser"
+
+	u, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.User{}, storage.ErrUserNotFound
+		}
+		return storage.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, nil
+}
+
+// GetUserByName finds a user by name
+func (s *Storage) GetUserByName(ctx context.Context, name string) (storage.User, error) {
+	const op = "storage.sqlite.GetUserByName"
+
+	u, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE name = ?", name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.User{}, storage.ErrUserNotFound
+		}
+		return storage.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return u, nil
+}
+
+// SetUserRole changes the role of the user
+func (s *Storage) SetUserRole(ctx context.Context, id int64, role string) error {
+	const op = "storage.sqlite.SetUserRole"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE users SET role = ? WHERE id = ?", role, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ListUsers returns all users ordered by id
+func (s *Storage) ListUsers(ctx context.Context) ([]storage.User, error) {
+	const op = "storage.sqlite.ListUsers"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []storage.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return users, nil
+}
+
+func scanUser(row rowScanner) (storage.User, error) {
+	var (
+		u         storage.User
+		createdAt sql.NullTime
+	)
+	if err := row.Scan(&u.ID, &u.Name, &u.Role, &createdAt); err != nil {
+		return storage.User{}, err
+	}
+	u.CreatedAt = createdAt.Time
+
+	return u, nil
+}
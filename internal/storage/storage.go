@@ -1,15 +1,178 @@
 package storage
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"time"
+)
 
 var (
 	ErrURLNotFound = errors.New("url not found")
 	ErrURLExists   = errors.New("url already exists")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+
+	ErrUserNotFound = errors.New("user not found")
+	ErrUserExists   = errors.New("user already exists")
+)
+
+// URL is a short link as it is kept in the storage
+type URL struct {
+	ID    int64
+	Alias string
+	URL   string
+	// RedirectCode is the HTTP status used to redirect this link,
+	// zero means the server default
+	RedirectCode int
+	// ExpiresAt is the moment the link stops working, zero means never
+	ExpiresAt time.Time
+	// CreatedAt is zero for links saved before it was recorded
+	CreatedAt time.Time
+	// OwnerID is the user who saved the link, zero for links saved before owners were recorded
+	OwnerID int64
+}
+
+// Expired reports whether the link has expired at the given moment
+func (u URL) Expired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && !now.Before(u.ExpiresAt)
+}
+
+// sort keys accepted by ListURLs
+const (
+	SortByID        = "id"
+	SortByAlias     = "alias"
+	SortByURL       = "url"
+	SortByCreatedAt = "created_at"
+)
+
+// ListParams selects a page of links
+type ListParams struct {
+	Limit  int
+	Offset int
+	// SortBy is one of the SortBy* keys, links are sorted by id when empty
+	SortBy string
+	Desc   bool
+	// Query keeps only links whose alias or url contains it, ignoring case
+	Query string
+	// OwnerID keeps only links of this user, zero means links of all users
+	OwnerID int64
+}
+
+// Click is one resolved redirect
+type Click struct {
+	URLID      int64
+	Alias      string
+	ClickedAt  time.Time
+	Referrer   string
+	UserAgent  string
+	RemoteAddr string
+	RequestID  string
+}
+
+// ClickStats is the number of clicks on a link, in total and per day
+type ClickStats struct {
+	Total int64
+	Daily []DailyClicks
+}
+
+// DailyClicks is the number of clicks on one UTC day
+type DailyClicks struct {
+	Day   string // 2006-01-02
+	Count int64
+}
+
+// APIKey is a client credential. Only a hash of the key is stored,
+// the key itself is shown once when it is created.
+type APIKey struct {
+	ID     int64
+	UserID int64
+	Name   string
+	// Prefix is the beginning of the key, to tell keys apart in listings
+	Prefix    string
+	Hash      string
+	Scopes    []string
+	CreatedAt time.Time
+	// RevokedAt is zero for active keys
+	RevokedAt time.Time
+}
+
+// Revoked reports whether the key may no longer be used
+func (k APIKey) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// HasScope reports whether the key grants the scope
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// roles of users, each one may do everything the previous one may
+const (
+	// RoleViewer lists and reads only its own links, e.g. assigned with
+	// `link create -owner`, and reads click stats of any link. It cannot create links.
+	RoleViewer = "viewer"
+	// RoleEditor also creates links and changes or deletes their own ones
+	RoleEditor = "editor"
+	// RoleAdmin also reads and manages links of every user
+	RoleAdmin = "admin"
 )
 
+// Roles are all roles from the least to the most privileged
+var Roles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+// User owns links and api keys
+type User struct {
+	ID        int64
+	Name      string
+	Role      string
+	CreatedAt time.Time
+}
+
+// IsAdmin reports whether the user may manage links of every user
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// HasRole reports whether the user's role is the given one or a more privileged one
+func (u User) HasRole(role string) bool {
+	return roleRank(u.Role) >= roleRank(role) && roleRank(role) >= 0
+}
+
+// ValidRole reports whether the role is one of Roles
+func ValidRole(role string) bool {
+	return roleRank(role) >= 0
+}
+
+// roleRank is the position of the role in Roles, -1 for unknown roles
+func roleRank(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
 // URLStorage defines the interface for URL storage operations
 type URLStorage interface {
-	SaveURL(urlToSave, alias string) (int64, error)
-	GetURL(alias string) (string, error)
-	DeleteURL(alias string) error
+	SaveURL(ctx context.Context, u URL) (int64, error)
+	GetURL(ctx context.Context, alias string) (URL, error)
+	DeleteURL(ctx context.Context, alias string) error
+	// ListURLs returns a page of links and the number of links matching params
+	ListURLs(ctx context.Context, params ListParams) ([]URL, int64, error)
+	// UpdateURL changes the target url of the alias
+	UpdateURL(ctx context.Context, alias, newURL string) error
+	// GetURLByTarget returns the oldest link of the owner to the same url
+	// after normalization, links that expire are skipped
+	GetURLByTarget(ctx context.Context, ownerID int64, target string) (URL, error)
+	// SaveURLs saves links in one transaction and returns their ids in order,
+	// zero for links whose alias is taken. With allOrNothing nothing is saved
+	// if any alias is taken: ErrURLExists is returned and zero ids mark the
+	// taken aliases.
+	SaveURLs(ctx context.Context, urls []URL, allOrNothing bool) ([]int64, error)
 }
@@ -0,0 +1,167 @@
+// Package timeout limits how long each storage operation may run.
+package timeout
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// Timeouts per operation, zero means no limit besides the caller's context
+type Timeouts struct {
+	Get    time.Duration
+	Save   time.Duration
+	Delete time.Duration
+	List   time.Duration
+	Update time.Duration
+	// Batch limits a whole SaveURLs call
+	Batch time.Duration
+	// Auth limits api key and user lookups of every authenticated request
+	Auth time.Duration
+	// Stats limits click stats aggregation
+	Stats time.Duration
+	// Clicks limits writing a batch of clicks
+	Clicks time.Duration
+}
+
+// Storage wraps another storage.URLStorage and puts a deadline on
+// the context of every call.
+type Storage struct {
+	next     storage.URLStorage
+	timeouts Timeouts
+}
+
+func New(next storage.URLStorage, timeouts Timeouts) *Storage {
+	return &Storage{
+		next:     next,
+		timeouts: timeouts,
+	}
+}
+
+func (s *Storage) SaveURL(ctx context.Context, u storage.URL) (int64, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Save)
+	defer cancel()
+
+	return s.next.SaveURL(ctx, u)
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.URL, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Get)
+	defer cancel()
+
+	return s.next.GetURL(ctx, alias)
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Delete)
+	defer cancel()
+
+	return s.next.DeleteURL(ctx, alias)
+}
+
+func (s *Storage) ListURLs(ctx context.Context, params storage.ListParams) ([]storage.URL, int64, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.List)
+	defer cancel()
+
+	return s.next.ListURLs(ctx, params)
+}
+
+func (s *Storage) UpdateURL(ctx context.Context, alias, newURL string) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Update)
+	defer cancel()
+
+	return s.next.UpdateURL(ctx, alias, newURL)
+}
+
+func (s *Storage) GetURLByTarget(ctx context.Context, ownerID int64, target string) (storage.URL, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Get)
+	defer cancel()
+
+	return s.next.GetURLByTarget(ctx, ownerID, target)
+}
+
+func (s *Storage) SaveURLs(ctx context.Context, urls []storage.URL, allOrNothing bool) ([]int64, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Batch)
+	defer cancel()
+
+	return s.next.SaveURLs(ctx, urls, allOrNothing)
+}
+
+// Backend is the rest of what the server asks of the storage besides links
+type Backend interface {
+	storage.URLStorage
+	SaveClicks(ctx context.Context, clicks []storage.Click) error
+	ClickStats(ctx context.Context, alias string, since time.Time) (storage.ClickStats, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+	ArchiveExpired(ctx context.Context, now time.Time) (int64, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error)
+	GetUser(ctx context.Context, id int64) (storage.User, error)
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
+}
+
+// BackendStorage is Storage for a whole Backend. The sweeper and health
+// checks are not limited here, they run with deadlines of their own.
+type BackendStorage struct {
+	*Storage
+	next Backend
+}
+
+func NewBackend(next Backend, timeouts Timeouts) *BackendStorage {
+	return &BackendStorage{
+		Storage: New(next, timeouts),
+		next:    next,
+	}
+}
+
+func (s *BackendStorage) SaveClicks(ctx context.Context, clicks []storage.Click) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Clicks)
+	defer cancel()
+
+	return s.next.SaveClicks(ctx, clicks)
+}
+
+func (s *BackendStorage) ClickStats(ctx context.Context, alias string, since time.Time) (storage.ClickStats, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Stats)
+	defer cancel()
+
+	return s.next.ClickStats(ctx, alias, since)
+}
+
+func (s *BackendStorage) GetAPIKeyByHash(ctx context.Context, hash string) (storage.APIKey, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Auth)
+	defer cancel()
+
+	return s.next.GetAPIKeyByHash(ctx, hash)
+}
+
+func (s *BackendStorage) GetUser(ctx context.Context, id int64) (storage.User, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Auth)
+	defer cancel()
+
+	return s.next.GetUser(ctx, id)
+}
+
+func (s *BackendStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return s.next.DeleteExpired(ctx, now)
+}
+
+func (s *BackendStorage) ArchiveExpired(ctx context.Context, now time.Time) (int64, error) {
+	return s.next.ArchiveExpired(ctx, now)
+}
+
+func (s *BackendStorage) Ping(ctx context.Context) error {
+	return s.next.Ping(ctx)
+}
+
+func (s *BackendStorage) SchemaVersion(ctx context.Context) (int, error) {
+	return s.next.SchemaVersion(ctx)
+}
+
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,172 @@
+package timeout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"url-shortener/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingStorage waits for the context to be done on every call
+type blockingStorage struct{}
+
+func (blockingStorage) SaveURL(ctx context.Context, _ storage.URL) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func (blockingStorage) GetURL(ctx context.Context, _ string) (storage.URL, error) {
+	<-ctx.Done()
+	return storage.URL{}, ctx.Err()
+}
+
+func (blockingStorage) DeleteURL(ctx context.Context, _ string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingStorage) ListURLs(ctx context.Context, _ storage.ListParams) ([]storage.URL, int64, error) {
+	<-ctx.Done()
+	return nil, 0, ctx.Err()
+}
+
+func (blockingStorage) UpdateURL(ctx context.Context, _, _ string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingStorage) GetURLByTarget(ctx context.Context, _ int64, _ string) (storage.URL, error) {
+	<-ctx.Done()
+	return storage.URL{}, ctx.Err()
+}
+
+func (blockingStorage) SaveURLs(ctx context.Context, _ []storage.URL, _ bool) ([]int64, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestTimeouts(t *testing.T) {
+	s := New(blockingStorage{}, Timeouts{
+		Get:    time.Millisecond,
+		Save:   time.Millisecond,
+		Delete: time.Millisecond,
+		List:   time.Millisecond,
+		Update: time.Millisecond,
+		Batch:  time.Millisecond,
+	})
+
+	ctx := context.Background()
+
+	_, err := s.GetURL(ctx, "alias")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = s.SaveURL(ctx, storage.URL{Alias: "alias"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = s.DeleteURL(ctx, "alias")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, _, err = s.ListURLs(ctx, storage.ListParams{Limit: 10})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = s.UpdateURL(ctx, "alias", "https://example.com")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = s.GetURLByTarget(ctx, 1, "https://example.com")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = s.SaveURLs(ctx, []storage.URL{{Alias: "alias"}}, true)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// blockingBackend blocks on the calls of the server besides links
+type blockingBackend struct {
+	blockingStorage
+}
+
+func (blockingBackend) SaveClicks(ctx context.Context, _ []storage.Click) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingBackend) ClickStats(ctx context.Context, _ string, _ time.Time) (storage.ClickStats, error) {
+	<-ctx.Done()
+	return storage.ClickStats{}, ctx.Err()
+}
+
+func (blockingBackend) DeleteExpired(ctx context.Context, _ time.Time) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func (blockingBackend) ArchiveExpired(ctx context.Context, _ time.Time) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func (blockingBackend) GetAPIKeyByHash(ctx context.Context, _ string) (storage.APIKey, error) {
+	<-ctx.Done()
+	return storage.APIKey{}, ctx.Err()
+}
+
+func (blockingBackend) GetUser(ctx context.Context, _ int64) (storage.User, error) {
+	<-ctx.Done()
+	return storage.User{}, ctx.Err()
+}
+
+func (blockingBackend) Ping(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingBackend) SchemaVersion(ctx context.Context) (int, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestBackendTimeouts(t *testing.T) {
+	s := NewBackend(blockingBackend{}, Timeouts{
+		Get:    time.Millisecond,
+		Auth:   time.Millisecond,
+		Stats:  time.Millisecond,
+		Clicks: time.Millisecond,
+	})
+
+	ctx := context.Background()
+
+	_, err := s.GetURL(ctx, "alias")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = s.GetAPIKeyByHash(ctx, "hash")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = s.GetUser(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = s.ClickStats(ctx, "alias", time.Now())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = s.SaveClicks(ctx, []storage.Click{{URLID: 1}})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// у проверок здоровья и чистильщика свои сроки
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, s.Ping(cancelled), context.Canceled)
+	_, err = s.DeleteExpired(cancelled, time.Now())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCallerCancellation(t *testing.T) {
+	// no timeout configured: only the caller's context stops the call
+	s := New(blockingStorage{}, Timeouts{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.GetURL(ctx, "alias")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,67 @@
+// Package sweeper periodically removes expired links from the storage.
+package sweeper
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// ExpiredRemover is implemented by storages that can drop expired links.
+type ExpiredRemover interface {
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+	ArchiveExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type Sweeper struct {
+	log      *slog.Logger
+	remover  ExpiredRemover
+	interval time.Duration
+	archive  bool
+}
+
+// New creates sweeper that runs every interval. With archive set expired
+// links are moved to the archive instead of being deleted.
+func New(log *slog.Logger, remover ExpiredRemover, interval time.Duration, archive bool) *Sweeper {
+	return &Sweeper{
+		log:      log.With(slog.String("component", "sweeper")),
+		remover:  remover,
+		interval: interval,
+		archive:  archive,
+	}
+}
+
+// Run sweeps on every tick until ctx is cancelled.
+func (s *Sweeper) Run(ctx context.Context) {
+	s.log.Info("sweeper started", slog.Duration("interval", s.interval), slog.Bool("archive", s.archive))
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info("sweeper stopped")
+			return
+		case now := <-ticker.C:
+			n, err := s.Sweep(ctx, now)
+			if err != nil {
+				s.log.Error("failed to sweep expired urls", sl.Err(err))
+				continue
+			}
+			if n > 0 {
+				s.log.Info("expired urls swept", slog.Int64("count", n))
+			}
+		}
+	}
+}
+
+// Sweep removes links that expired before now once.
+func (s *Sweeper) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	if s.archive {
+		return s.remover.ArchiveExpired(ctx, now)
+	}
+	return s.remover.DeleteExpired(ctx, now)
+}
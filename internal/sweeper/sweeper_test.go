@@ -0,0 +1,60 @@
+package sweeper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now()
+
+	for _, archive := range []bool{false, true} {
+		s := memory.New()
+		_, err := s.SaveURL(ctx, storage.URL{Alias: "expired", URL: "https://example.com", ExpiresAt: now.Add(-time.Minute)})
+		require.NoError(t, err)
+		_, err = s.SaveURL(ctx, storage.URL{Alias: "alive", URL: "https://example.com", ExpiresAt: now.Add(time.Hour)})
+		require.NoError(t, err)
+		_, err = s.SaveURL(ctx, storage.URL{Alias: "forever", URL: "https://example.com"})
+		require.NoError(t, err)
+
+		n, err := New(slogdiscard.NewDiscardLogger(), s, time.Minute, archive).Sweep(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		_, err = s.GetURL(ctx, "expired")
+		assert.ErrorIs(t, err, storage.ErrURLNotFound)
+		_, err = s.GetURL(ctx, "alive")
+		assert.NoError(t, err)
+		_, err = s.GetURL(ctx, "forever")
+		assert.NoError(t, err)
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		New(slogdiscard.NewDiscardLogger(), memory.New(), time.Millisecond, false).Run(ctx)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sweeper did not stop after cancel")
+	}
+}
@@ -0,0 +1,259 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"url-shortener/internal/storage"
+)
+
+// columns lists the accepted header names of each field. Names are
+// compared after normalizeHeader, so "Long URL" matches "long_url".
+type columns struct {
+	ID    []string
+	Alias []string
+	// ShortLink is the full short link, its path is used when there is no alias
+	ShortLink    []string
+	URL          []string
+	RedirectCode []string
+	CreatedAt    []string
+	ExpiresAt    []string
+	OwnerID      []string
+}
+
+// nativeColumns are written by export
+var nativeColumns = columns{
+	ID:           []string{"id"},
+	Alias:        []string{"alias"},
+	URL:          []string{"url"},
+	RedirectCode: []string{"redirect_code"},
+	CreatedAt:    []string{"created_at"},
+	ExpiresAt:    []string{"expires_at"},
+	OwnerID:      []string{"owner_id"},
+}
+
+var csvHeader = []string{"id", "alias", "url", "redirect_code", "created_at", "expires_at", "owner_id"}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, err
+	}
+
+	return &csvWriter{w: cw}, nil
+}
+
+func (w *csvWriter) Write(u storage.URL) error {
+	return w.w.Write([]string{
+		strconv.FormatInt(u.ID, 10),
+		u.Alias,
+		u.URL,
+		formatInt(int64(u.RedirectCode)),
+		formatTime(u.CreatedAt),
+		formatTime(u.ExpiresAt),
+		formatInt(u.OwnerID),
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// пустые ячейки вместо нулей, как и в JSON
+func formatInt(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// csvReader finds the fields by the header row, other columns are ignored
+type csvReader struct {
+	r *csv.Reader
+	// index of each field in a row, -1 when there is no such column
+	id, alias, shortLink, url, redirectCode, createdAt, expiresAt, ownerID int
+}
+
+func newCSVReader(r io.Reader, cols columns) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("the file is empty, expected a header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		// Excel начинает файл с BOM
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		if _, ok := index[normalizeHeader(name)]; !ok {
+			index[normalizeHeader(name)] = i
+		}
+	}
+
+	find := func(names []string) int {
+		for _, name := range names {
+			if i, ok := index[normalizeHeader(name)]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	res := &csvReader{
+		r:            cr,
+		id:           find(cols.ID),
+		alias:        find(cols.Alias),
+		shortLink:    find(cols.ShortLink),
+		url:          find(cols.URL),
+		redirectCode: find(cols.RedirectCode),
+		createdAt:    find(cols.CreatedAt),
+		expiresAt:    find(cols.ExpiresAt),
+		ownerID:      find(cols.OwnerID),
+	}
+	if res.url == -1 {
+		return nil, fmt.Errorf("no url column in the header, expected one of %s", strings.Join(cols.URL, ", "))
+	}
+
+	return res, nil
+}
+
+func (r *csvReader) Read() (Record, error) {
+	row, err := r.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Record{}, &RowError{Line: parseErr.Line, Err: parseErr.Err}
+		}
+		return Record{}, err
+	}
+
+	line, _ := r.r.FieldPos(0)
+	rec := Record{Line: line}
+
+	cell := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	rowErr := func(field string, err error) (Record, error) {
+		return Record{}, &RowError{Line: line, Err: fmt.Errorf("invalid %s: %w", field, err)}
+	}
+
+	rec.URL.URL = cell(r.url)
+	rec.Alias = cell(r.alias)
+	if rec.Alias == "" {
+		rec.Alias = aliasFromShortLink(cell(r.shortLink))
+	}
+
+	if rec.ID, err = parseInt(cell(r.id)); err != nil {
+		return rowErr("id", err)
+	}
+	if rec.OwnerID, err = parseInt(cell(r.ownerID)); err != nil {
+		return rowErr("owner id", err)
+	}
+	code, err := parseInt(cell(r.redirectCode))
+	if err != nil {
+		return rowErr("redirect code", err)
+	}
+	rec.RedirectCode = int(code)
+	if rec.CreatedAt, err = parseTime(cell(r.createdAt)); err != nil {
+		return rowErr("creation time", err)
+	}
+	if rec.ExpiresAt, err = parseTime(cell(r.expiresAt)); err != nil {
+		return rowErr("expiry time", err)
+	}
+
+	return rec, nil
+}
+
+// normalizeHeader drops case, spaces, dashes and underscores: "Long URL" -> "longurl"
+func normalizeHeader(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// aliasFromShortLink takes the path of a short link: "https://bit.ly/3xYz" -> "3xYz".
+// Links are often exported without a scheme.
+func aliasFromShortLink(s string) string {
+	if s == "" {
+		return ""
+	}
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+
+	return strings.Trim(u.Path, "/")
+}
+
+func parseInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// timeLayouts are tried in order, exports of other services use all of them
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"01/02/2006",
+}
+
+// parseTime reads times without a zone as UTC
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown time format %q", s)
+}
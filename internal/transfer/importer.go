@@ -0,0 +1,289 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"url-shortener/internal/http-server/handlers/url/save"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/storage"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// what to do with a link whose alias is already taken
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictRename    = "rename"
+)
+
+// Conflicts are all conflict resolutions
+var Conflicts = []string{ConflictSkip, ConflictOverwrite, ConflictRename}
+
+// results of importing one link
+const (
+	ResultCreated     = "created"
+	ResultOverwritten = "overwritten"
+	ResultRenamed     = "renamed"
+	ResultSkipped     = "skipped"
+	ResultInvalid     = "invalid"
+)
+
+// maxRetries is how many times a taken generated alias is generated again
+const maxRetries = 4
+
+// URLSaver is the storage as seen by the Importer
+type URLSaver interface {
+	SaveURL(ctx context.Context, u storage.URL) (int64, error)
+	// ReplaceURL overwrites the link with the same alias, keeping its id
+	ReplaceURL(ctx context.Context, u storage.URL) (int64, error)
+	GetUser(ctx context.Context, id int64) (storage.User, error)
+}
+
+// Event describes what happened to one link of the file
+type Event struct {
+	Line   int
+	Alias  string
+	Result string
+	// Message explains skipped and invalid links, names the new alias of
+	// renamed ones and tells when the owner of the file was dropped
+	Message string
+}
+
+// Summary counts the links of the file by result
+type Summary struct {
+	Created     int
+	Overwritten int
+	Renamed     int
+	Skipped     int
+	Invalid     int
+}
+
+// Total is the number of links read from the file
+func (s Summary) Total() int {
+	return s.Created + s.Overwritten + s.Renamed + s.Skipped + s.Invalid
+}
+
+func (s *Summary) add(result string) {
+	switch result {
+	case ResultCreated:
+		s.Created++
+	case ResultOverwritten:
+		s.Overwritten++
+	case ResultRenamed:
+		s.Renamed++
+	case ResultSkipped:
+		s.Skipped++
+	case ResultInvalid:
+		s.Invalid++
+	}
+}
+
+// Importer saves links read from files. Every link is checked with the
+// rules of POST /url, ids of the file are not kept.
+type Importer struct {
+	links      URLSaver
+	validate   *validator.Validate
+	aliases    save.AliasGenerator
+	policy     save.AliasPolicy
+	onConflict string
+	ownerID    int64
+	// owners caches whether owners of the file exist in the storage
+	owners map[int64]bool
+}
+
+// NewImporter returns an importer that resolves taken aliases with
+// onConflict, one of Conflicts. A non-zero ownerID replaces owners of the file.
+// Otherwise an owner of the file is kept only if a user with its id exists,
+// links of unknown owners are saved without one.
+func NewImporter(links URLSaver, aliases save.AliasGenerator, policy save.AliasPolicy, onConflict string, ownerID int64) (*Importer, error) {
+	switch onConflict {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+	default:
+		return nil, fmt.Errorf("unknown conflict resolution %q, expected %s", onConflict, strings.Join(Conflicts, ", "))
+	}
+
+	return &Importer{
+		links:      links,
+		validate:   save.NewValidator(policy),
+		aliases:    aliases,
+		policy:     policy,
+		onConflict: onConflict,
+		ownerID:    ownerID,
+		owners:     make(map[int64]bool),
+	}, nil
+}
+
+// Import saves every link of r and calls report, which may be nil, for each
+// of them. Broken and invalid links are counted and skipped, a storage
+// error stops the import.
+func (im *Importer) Import(ctx context.Context, r Reader, report func(Event)) (Summary, error) {
+	const op = "transfer.Import"
+
+	if report == nil {
+		report = func(Event) {}
+	}
+
+	var summary Summary
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return summary, nil
+		}
+
+		var ev Event
+		var rowErr *RowError
+		switch {
+		case errors.As(err, &rowErr):
+			ev = Event{Line: rowErr.Line, Result: ResultInvalid, Message: rowErr.Err.Error()}
+		case err != nil:
+			return summary, fmt.Errorf("%s: %w", op, err)
+		default:
+			ev, err = im.save(ctx, rec)
+			if err != nil {
+				return summary, fmt.Errorf("%s: line %d: %w", op, rec.Line, err)
+			}
+		}
+
+		summary.add(ev.Result)
+		report(ev)
+	}
+}
+
+// save validates and saves one link, the error is returned only when the storage fails
+func (im *Importer) save(ctx context.Context, rec Record) (Event, error) {
+	ev := Event{Line: rec.Line, Alias: rec.Alias}
+
+	req := save.Request{
+		URL:          rec.URL.URL,
+		Alias:        im.policy.Normalize(rec.Alias),
+		RedirectCode: rec.RedirectCode,
+	}
+	if !rec.ExpiresAt.IsZero() {
+		req.ExpiresAt = &rec.ExpiresAt
+	}
+
+	if err := im.validate.Struct(req); err != nil {
+		var validateErr validator.ValidationErrors
+		if !errors.As(err, &validateErr) {
+			return ev, err
+		}
+		ev.Result, ev.Message = ResultInvalid, resp.ValidationError(validateErr).Error
+		return ev, nil
+	}
+
+	expiresAt, fieldErr := req.Expiry(time.Now())
+	if fieldErr != nil {
+		ev.Result, ev.Message = ResultInvalid, fieldErr.Message
+		return ev, nil
+	}
+
+	u := storage.URL{
+		Alias:        req.Alias,
+		URL:          req.URL,
+		RedirectCode: req.RedirectCode,
+		ExpiresAt:    expiresAt,
+		CreatedAt:    rec.CreatedAt,
+		OwnerID:      im.ownerID,
+	}
+	if u.OwnerID == 0 && rec.OwnerID != 0 {
+		ok, err := im.ownerExists(ctx, rec.OwnerID)
+		if err != nil {
+			return ev, err
+		}
+		if ok {
+			u.OwnerID = rec.OwnerID
+		} else {
+			ev.Message = fmt.Sprintf("owner %d does not exist, saved without an owner", rec.OwnerID)
+		}
+	}
+
+	// ссылки без алиаса получают сгенерированный, как в POST /url
+	if u.Alias == "" {
+		alias, err := im.saveGenerated(ctx, u)
+		if err != nil {
+			return ev, err
+		}
+		ev.Alias, ev.Result = alias, ResultCreated
+		return ev, nil
+	}
+
+	_, err := im.links.SaveURL(ctx, u)
+	if err == nil {
+		ev.Alias, ev.Result = u.Alias, ResultCreated
+		return ev, nil
+	}
+	if !errors.Is(err, storage.ErrURLExists) {
+		return ev, err
+	}
+
+	switch im.onConflict {
+	case ConflictOverwrite:
+		// одно обновление вместо удаления и вставки: ссылка сохраняет id и переходы
+		if _, err := im.links.ReplaceURL(ctx, u); err != nil {
+			return ev, err
+		}
+		ev.Alias, ev.Result = u.Alias, ResultOverwritten
+	case ConflictRename:
+		alias, err := im.saveGenerated(ctx, u)
+		if err != nil {
+			return ev, err
+		}
+		ev.Alias, ev.Result = alias, ResultRenamed
+		ev.Message = joinMessages(fmt.Sprintf("alias %q is taken, saved as %q", u.Alias, alias), ev.Message)
+	default:
+		ev.Result, ev.Message = ResultSkipped, fmt.Sprintf("alias %q is taken", u.Alias)
+	}
+
+	return ev, nil
+}
+
+// ownerExists reports whether the owner of the file is a user of the storage,
+// ids of another instance may belong to somebody else or to nobody
+func (im *Importer) ownerExists(ctx context.Context, id int64) (bool, error) {
+	if ok, cached := im.owners[id]; cached {
+		return ok, nil
+	}
+
+	_, err := im.links.GetUser(ctx, id)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return false, err
+	}
+
+	im.owners[id] = err == nil
+
+	return err == nil, nil
+}
+
+func joinMessages(a, b string) string {
+	if b == "" {
+		return a
+	}
+	return a + "; " + b
+}
+
+// saveGenerated saves the link under a generated alias and returns the alias
+func (im *Importer) saveGenerated(ctx context.Context, u storage.URL) (string, error) {
+	for attempt := 0; ; attempt++ {
+		alias, err := save.GenerateAlias(im.aliases, im.policy)
+		if err != nil {
+			return "", err
+		}
+		u.Alias = alias
+
+		_, err = im.links.SaveURL(ctx, u)
+		if errors.Is(err, storage.ErrURLExists) && attempt < maxRetries {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return alias, nil
+	}
+}
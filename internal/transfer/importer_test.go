@@ -0,0 +1,170 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const importData = `{"alias":"taken","url":"https://new.example"}
+{"alias":"fresh","url":"https://fresh.example","created_at":"2020-01-01T00:00:00Z","owner_id":3}
+{"url":"https://generated.example"}
+{"alias":"bad","url":"not a url"}
+{"alias":"admin","url":"https://admin.example"}
+{"alias":"old","url":"https://old.example","expires_at":"2001-01-01T00:00:00Z"}
+{broken
+`
+
+func newTestImporter(t *testing.T, store URLSaver, onConflict string, ownerID int64) *Importer {
+	t.Helper()
+
+	policy := alias.NewPolicy(alias.Rules{
+		MinLength: 3,
+		MaxLength: 64,
+		Charset:   "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_",
+		Reserved:  []string{"admin"},
+	})
+
+	im, err := NewImporter(store, alias.NewRandom(6), policy, onConflict, ownerID)
+	require.NoError(t, err)
+
+	return im
+}
+
+func TestImport(t *testing.T) {
+	cases := []struct {
+		onConflict  string
+		wantSummary Summary
+		// wantTaken is the url of the "taken" alias after the import
+		wantTaken string
+	}{
+		{
+			onConflict:  ConflictSkip,
+			wantSummary: Summary{Created: 2, Skipped: 1, Invalid: 4},
+			wantTaken:   "https://old.example/taken",
+		},
+		{
+			onConflict:  ConflictOverwrite,
+			wantSummary: Summary{Created: 2, Overwritten: 1, Invalid: 4},
+			wantTaken:   "https://new.example",
+		},
+		{
+			onConflict:  ConflictRename,
+			wantSummary: Summary{Created: 2, Renamed: 1, Invalid: 4},
+			wantTaken:   "https://old.example/taken",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.onConflict, func(t *testing.T) {
+			ctx := context.Background()
+
+			store := memory.New()
+			takenID, err := store.SaveURL(ctx, storage.URL{Alias: "taken", URL: "https://old.example/taken"})
+			require.NoError(t, err)
+
+			r, err := NewReader(strings.NewReader(importData), FormatJSONL)
+			require.NoError(t, err)
+
+			var events []Event
+			summary, err := newTestImporter(t, store, tc.onConflict, 0).Import(ctx, r, func(ev Event) {
+				events = append(events, ev)
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantSummary, summary)
+			assert.Equal(t, 7, summary.Total())
+			require.Len(t, events, 7)
+
+			taken, err := store.GetURL(ctx, "taken")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantTaken, taken.URL)
+			// перезаписанная ссылка остаётся той же записью
+			assert.Equal(t, takenID, taken.ID)
+
+			// пользователя 3 нет, ссылка сохраняется без владельца
+			fresh, err := store.GetURL(ctx, "fresh")
+			require.NoError(t, err)
+			assert.Zero(t, fresh.OwnerID)
+			assert.Equal(t, 2020, fresh.CreatedAt.Year())
+			assert.Equal(t, ResultCreated, events[1].Result)
+			assert.Contains(t, events[1].Message, "owner 3")
+
+			// сгенерированный алиас
+			assert.Equal(t, ResultCreated, events[2].Result)
+			assert.Len(t, events[2].Alias, 6)
+
+			for _, i := range []int{3, 4, 5, 6} {
+				assert.Equal(t, ResultInvalid, events[i].Result, "line %d", events[i].Line)
+				assert.NotEmpty(t, events[i].Message)
+			}
+			assert.Contains(t, events[4].Message, "reserved")
+
+			if tc.onConflict == ConflictRename {
+				renamed, err := store.GetURL(ctx, events[0].Alias)
+				require.NoError(t, err)
+				assert.Equal(t, "https://new.example", renamed.URL)
+				assert.Contains(t, events[0].Message, "taken")
+			}
+		})
+	}
+}
+
+func TestImportOverridesOwner(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	r, err := NewReader(strings.NewReader(importData), FormatJSONL)
+	require.NoError(t, err)
+
+	_, err = newTestImporter(t, store, ConflictSkip, 9).Import(ctx, r, nil)
+	require.NoError(t, err)
+
+	fresh, err := store.GetURL(ctx, "fresh")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), fresh.OwnerID)
+}
+
+func TestImportKeepsExistingOwner(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+
+	userID, err := store.SaveUser(ctx, storage.User{Name: "alice", Role: storage.RoleEditor})
+	require.NoError(t, err)
+
+	data := fmt.Sprintf(`{"alias":"mine","url":"https://mine.example","owner_id":%d}
+{"alias":"foreign","url":"https://foreign.example","owner_id":%d}
+`, userID, userID+100)
+
+	r, err := NewReader(strings.NewReader(data), FormatJSONL)
+	require.NoError(t, err)
+
+	var events []Event
+	summary, err := newTestImporter(t, store, ConflictSkip, 0).Import(ctx, r, func(ev Event) {
+		events = append(events, ev)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Created: 2}, summary)
+
+	mine, err := store.GetURL(ctx, "mine")
+	require.NoError(t, err)
+	assert.Equal(t, userID, mine.OwnerID)
+	assert.Empty(t, events[0].Message)
+
+	foreign, err := store.GetURL(ctx, "foreign")
+	require.NoError(t, err)
+	assert.Zero(t, foreign.OwnerID)
+	assert.NotEmpty(t, events[1].Message)
+}
+
+func TestNewImporterRejectsUnknownConflict(t *testing.T) {
+	_, err := NewImporter(memory.New(), alias.NewRandom(6), alias.NewPolicy(alias.Rules{}), "merge", 0)
+	assert.ErrorContains(t, err, "merge")
+}
@@ -0,0 +1,82 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"url-shortener/internal/lib/api/link"
+	"url-shortener/internal/storage"
+)
+
+// maxLineSize limits one JSON line, links are much shorter
+const maxLineSize = 1 << 20
+
+// в JSON Lines каждая ссылка записана так же, как её отдаёт API
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	bw := bufio.NewWriter(w)
+	return &jsonlWriter{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (w *jsonlWriter) Write(u storage.URL) error {
+	return w.enc.Encode(link.FromURL(u))
+}
+
+func (w *jsonlWriter) Flush() error {
+	return w.w.Flush()
+}
+
+type jsonlReader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &jsonlReader{sc: sc}
+}
+
+func (r *jsonlReader) Read() (Record, error) {
+	for r.sc.Scan() {
+		r.line++
+
+		data := bytes.TrimSpace(r.sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var l link.Link
+		if err := json.Unmarshal(data, &l); err != nil {
+			return Record{}, &RowError{Line: r.line, Err: err}
+		}
+
+		rec := Record{Line: r.line, URL: storage.URL{
+			ID:           l.ID,
+			Alias:        l.Alias,
+			URL:          l.URL,
+			RedirectCode: l.RedirectCode,
+			OwnerID:      l.OwnerID,
+		}}
+		if l.CreatedAt != nil {
+			rec.CreatedAt = *l.CreatedAt
+		}
+		if l.ExpiresAt != nil {
+			rec.ExpiresAt = *l.ExpiresAt
+		}
+
+		return rec, nil
+	}
+
+	if err := r.sc.Err(); err != nil {
+		return Record{}, err
+	}
+
+	return Record{}, io.EOF
+}
@@ -0,0 +1,123 @@
+// Package transfer moves links in and out of the storage as files:
+// CSV and JSON Lines written by export, and the CSV exports of other
+// link shorteners for migrations. Files are read and written row by row,
+// so they may be larger than memory.
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"url-shortener/internal/storage"
+)
+
+// formats of files
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// Formats are the formats export writes, import also reads the Vendors formats
+var Formats = []string{FormatJSONL, FormatCSV}
+
+// Writer writes links one at a time, Flush must be called at the end
+type Writer interface {
+	Write(u storage.URL) error
+	Flush() error
+}
+
+// Reader reads links one at a time and returns io.EOF at the end.
+// A broken row is returned as *RowError, reading may go on after it.
+type Reader interface {
+	Read() (Record, error)
+}
+
+// Record is a link read from a file
+type Record struct {
+	// Line is the number of the line or row in the file, starting at 1
+	Line int
+	storage.URL
+}
+
+// RowError tells which row of the file could not be read
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// NewWriter returns a writer of one of Formats
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	case FormatCSV:
+		return newCSVWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown export format %q, expected %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// NewReader returns a reader of one of Formats or of a vendor format
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatJSONL:
+		return newJSONLReader(r), nil
+	case FormatCSV:
+		return newCSVReader(r, nativeColumns)
+	}
+
+	if v, ok := vendor(format); ok {
+		return newCSVReader(r, v.columns)
+	}
+
+	return nil, fmt.Errorf("unknown import format %q, expected %s or one of %s",
+		format, strings.Join(Formats, ", "), strings.Join(VendorNames(), ", "))
+}
+
+// Lister is the storage as seen by Export
+type Lister interface {
+	ListURLs(ctx context.Context, params storage.ListParams) ([]storage.URL, int64, error)
+}
+
+// exportPageSize is how many links Export reads from the storage at once
+const exportPageSize = 500
+
+// Export writes every link in the order of ids and returns how many were written
+func Export(ctx context.Context, links Lister, w Writer) (int, error) {
+	const op = "transfer.Export"
+
+	n := 0
+	for {
+		page, _, err := links.ListURLs(ctx, storage.ListParams{Limit: exportPageSize, Offset: n, SortBy: storage.SortByID})
+		if err != nil {
+			return n, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, u := range page {
+			if err := w.Write(u); err != nil {
+				return n, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		n += len(page)
+
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return n, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
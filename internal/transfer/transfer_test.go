@@ -0,0 +1,197 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, r Reader) ([]Record, []*RowError) {
+	t.Helper()
+
+	var (
+		records []Record
+		broken  []*RowError
+	)
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records, broken
+		}
+
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			broken = append(broken, rowErr)
+			continue
+		}
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store := memory.New()
+	for _, u := range []storage.URL{
+		{Alias: "google", URL: "https://www.google.com", CreatedAt: created},
+		{Alias: "promo", URL: "https://example.com/?a=1,b=2", RedirectCode: 301, CreatedAt: created, ExpiresAt: expires, OwnerID: 7},
+	} {
+		_, err := store.SaveURL(ctx, u)
+		require.NoError(t, err)
+	}
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, format)
+			require.NoError(t, err)
+
+			n, err := Export(ctx, store, w)
+			require.NoError(t, err)
+			assert.Equal(t, 2, n)
+
+			r, err := NewReader(&buf, format)
+			require.NoError(t, err)
+
+			records, broken := readAll(t, r)
+			require.Empty(t, broken)
+			require.Len(t, records, 2)
+
+			assert.Equal(t, "google", records[0].Alias)
+			assert.Equal(t, "https://www.google.com", records[0].URL.URL)
+			assert.True(t, created.Equal(records[0].CreatedAt))
+			assert.True(t, records[0].ExpiresAt.IsZero())
+
+			assert.Equal(t, "promo", records[1].Alias)
+			assert.Equal(t, "https://example.com/?a=1,b=2", records[1].URL.URL)
+			assert.Equal(t, 301, records[1].RedirectCode)
+			assert.True(t, expires.Equal(records[1].ExpiresAt))
+			assert.Equal(t, int64(7), records[1].OwnerID)
+		})
+	}
+}
+
+func TestCSVReaderReportsBrokenRows(t *testing.T) {
+	data := "url,alias,redirect_code,expires_at\n" +
+		"https://a.example,a,,\n" +
+		"https://b.example,b,moved,\n" +
+		"https://c.example,c,,next week\n" +
+		"https://d.example,d,302,2030-01-02\n"
+
+	r, err := NewReader(strings.NewReader(data), FormatCSV)
+	require.NoError(t, err)
+
+	records, broken := readAll(t, r)
+	require.Len(t, records, 2)
+	assert.Equal(t, "a", records[0].Alias)
+	assert.Equal(t, 5, records[1].Line)
+	assert.Equal(t, time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), records[1].ExpiresAt)
+
+	require.Len(t, broken, 2)
+	assert.Equal(t, 3, broken[0].Line)
+	assert.Contains(t, broken[0].Error(), "redirect code")
+	assert.Equal(t, 4, broken[1].Line)
+	assert.Contains(t, broken[1].Error(), "expiry time")
+}
+
+func TestCSVReaderNeedsURLColumn(t *testing.T) {
+	_, err := NewReader(strings.NewReader("alias,target\na,https://a.example\n"), FormatCSV)
+	assert.ErrorContains(t, err, "no url column")
+
+	_, err = NewReader(strings.NewReader(""), FormatCSV)
+	assert.Error(t, err)
+}
+
+func TestJSONLReaderSkipsBlankLines(t *testing.T) {
+	data := `{"alias":"a","url":"https://a.example"}
+
+not json
+{"alias":"b","url":"https://b.example","redirect_code":308}
+`
+	r, err := NewReader(strings.NewReader(data), FormatJSONL)
+	require.NoError(t, err)
+
+	records, broken := readAll(t, r)
+	require.Len(t, records, 2)
+	assert.Equal(t, 1, records[0].Line)
+	assert.Equal(t, 4, records[1].Line)
+	assert.Equal(t, 308, records[1].RedirectCode)
+
+	require.Len(t, broken, 1)
+	assert.Equal(t, 3, broken[0].Line)
+}
+
+func TestVendorFormats(t *testing.T) {
+	cases := []struct {
+		format    string
+		data      string
+		wantAlias string
+		wantURL   string
+		wantDate  time.Time
+	}{
+		{
+			format:    "bitly",
+			data:      "\ufeffTitle,Link,Long URL,Date Created,Clicks\nPromo,bit.ly/3xYzAbc,https://example.com/promo,2023-05-04 12:30:00,42\n",
+			wantAlias: "3xYzAbc",
+			wantURL:   "https://example.com/promo",
+			wantDate:  time.Date(2023, 5, 4, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			format:    "rebrandly",
+			data:      "id,slashtag,shortUrl,destination,createdAt\nab12,summer-sale,rebrand.ly/summer-sale,https://example.com/sale,2022-06-01T08:00:00Z\n",
+			wantAlias: "summer-sale",
+			wantURL:   "https://example.com/sale",
+			wantDate:  time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			format:    "shortio",
+			data:      "originalURL,path,shortURL,createdAt\nhttps://example.com/docs,docs,https://short.example/docs,2021-01-02\n",
+			wantAlias: "docs",
+			wantURL:   "https://example.com/docs",
+			wantDate:  time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			format:    "tinyurl",
+			data:      "url,tiny_url,alias,created_at\nhttps://example.com/t,https://tinyurl.com/mytiny,,\n",
+			wantAlias: "mytiny",
+			wantURL:   "https://example.com/t",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.format, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tc.data), tc.format)
+			require.NoError(t, err)
+
+			records, broken := readAll(t, r)
+			require.Empty(t, broken)
+			require.Len(t, records, 1)
+
+			assert.Equal(t, tc.wantAlias, records[0].Alias)
+			assert.Equal(t, tc.wantURL, records[0].URL.URL)
+			assert.Equal(t, tc.wantDate, records[0].CreatedAt)
+		})
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := NewReader(strings.NewReader(""), "xml")
+	assert.ErrorContains(t, err, "bitly")
+
+	_, err = NewWriter(io.Discard, "bitly")
+	assert.Error(t, err)
+}
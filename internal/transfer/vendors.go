@@ -0,0 +1,73 @@
+package transfer
+
+import "sort"
+
+// Vendor is the CSV export format of another link shortener. Their exports
+// change from time to time, so each field accepts several column names and
+// unknown columns, such as click counts or titles, are ignored.
+type Vendor struct {
+	Name    string
+	columns columns
+}
+
+// Vendors are the formats import can migrate links from
+var Vendors = []Vendor{
+	{
+		Name: "bitly",
+		columns: columns{
+			Alias:     []string{"custom_bitlink", "back_half"},
+			ShortLink: []string{"link", "bitlink", "short_url", "short_link"},
+			URL:       []string{"long_url", "destination_url", "original_url", "url"},
+			CreatedAt: []string{"created_at", "date_created", "created"},
+		},
+	},
+	{
+		Name: "rebrandly",
+		columns: columns{
+			Alias:     []string{"slashtag"},
+			ShortLink: []string{"shorturl", "short_url", "link"},
+			URL:       []string{"destination", "destination_url", "url"},
+			CreatedAt: []string{"createdat", "created_at", "created"},
+		},
+	},
+	{
+		Name: "shortio",
+		columns: columns{
+			Alias:     []string{"path", "slug"},
+			ShortLink: []string{"secureshorturl", "shorturl", "short_url"},
+			URL:       []string{"originalurl", "original_url", "url"},
+			CreatedAt: []string{"createdat", "created_at"},
+			ExpiresAt: []string{"expiresat", "expires_at"},
+		},
+	},
+	{
+		Name: "tinyurl",
+		columns: columns{
+			Alias:     []string{"alias"},
+			ShortLink: []string{"tiny_url", "tinyurl", "short_url"},
+			URL:       []string{"url", "long_url"},
+			CreatedAt: []string{"created_at", "date_created"},
+			ExpiresAt: []string{"expires_at"},
+		},
+	},
+}
+
+func vendor(name string) (Vendor, bool) {
+	for _, v := range Vendors {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Vendor{}, false
+}
+
+// VendorNames returns names of the Vendors in alphabetical order
+func VendorNames() []string {
+	names := make([]string, 0, len(Vendors))
+	for _, v := range Vendors {
+		names = append(names, v.Name)
+	}
+	sort.Strings(names)
+
+	return names
+}